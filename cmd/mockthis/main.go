@@ -5,6 +5,7 @@ import (
 	"os"
 
 	"github.com/nicobistolfi/mockthis-cli/internal/commands"
+	"github.com/nicobistolfi/mockthis-cli/internal/config"
 	"github.com/spf13/cobra"
 )
 
@@ -13,11 +14,18 @@ var (
 	commit      = "none"
 	date        = "unknown"
 	showVersion bool
+	profile     string
 )
 
 var rootCmd = &cobra.Command{
 	Use:   "mockthis",
 	Short: "MockThis - A CLI for managing mock API endpoints",
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		if err := config.UseProfile(profile); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	},
 	Run: func(cmd *cobra.Command, args []string) {
 		if showVersion {
 			fmt.Printf("Version: %s\n", version)
@@ -39,7 +47,24 @@ func init() {
 	rootCmd.AddCommand(commands.GetEndpointCmd)
 	rootCmd.AddCommand(commands.UpdateEndpointCmd)
 	rootCmd.AddCommand(commands.DeleteEndpointCmd)
+	rootCmd.AddCommand(commands.ServeCmd)
+	rootCmd.AddCommand(commands.ScenarioCmd)
+	rootCmd.AddCommand(commands.RecordCmd)
+	rootCmd.AddCommand(commands.InvokeCmd)
+	rootCmd.AddCommand(commands.VerifyCmd)
+	rootCmd.AddCommand(commands.ValidateCmd)
+	rootCmd.AddCommand(commands.SchemaCmd)
+	rootCmd.AddCommand(commands.MigrateCmd)
+	rootCmd.AddCommand(commands.ProjectCmd)
+	rootCmd.AddCommand(commands.GCCmd)
+	rootCmd.AddCommand(commands.BackupCmd)
+	rootCmd.AddCommand(commands.RestoreCmd)
+	rootCmd.AddCommand(commands.PullCmd)
+	rootCmd.AddCommand(commands.ExportCmd)
+	rootCmd.AddCommand(commands.DiffCmd)
+	rootCmd.AddCommand(commands.CloneCmd)
 
+	rootCmd.PersistentFlags().StringVar(&profile, "profile", os.Getenv(config.ProfileEnv), "Profile whose credentials are used, each logged in to its own account. Defaults to $"+config.ProfileEnv)
 	rootCmd.Flags().BoolVarP(&showVersion, "version", "v", false, "Show version information")
 }
 
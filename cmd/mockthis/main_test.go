@@ -26,6 +26,22 @@ func TestCommandInitialization(t *testing.T) {
 		"get":      commands.GetEndpointCmd,
 		"update":   commands.UpdateEndpointCmd,
 		"delete":   commands.DeleteEndpointCmd,
+		"serve":    commands.ServeCmd,
+		"scenario": commands.ScenarioCmd,
+		"record":   commands.RecordCmd,
+		"invoke":   commands.InvokeCmd,
+		"verify":   commands.VerifyCmd,
+		"validate": commands.ValidateCmd,
+		"schema":   commands.SchemaCmd,
+		"migrate":  commands.MigrateCmd,
+		"project":  commands.ProjectCmd,
+		"gc":       commands.GCCmd,
+		"backup":   commands.BackupCmd,
+		"restore":  commands.RestoreCmd,
+		"pull":     commands.PullCmd,
+		"diff":     commands.DiffCmd,
+		"clone":    commands.CloneCmd,
+		"export":   commands.ExportCmd,
 	}
 
 	for name, expectedCmd := range expectedCommands {
@@ -48,7 +64,7 @@ func TestMainFunction(t *testing.T) {
 		t.Error("Expected rootCmd to be initialized, but it's nil")
 	}
 
-	if len(rootCmd.Commands()) != 7 {
-		t.Errorf("Expected rootCmd to have 7 subcommands, but got %d", len(rootCmd.Commands()))
+	if len(rootCmd.Commands()) != 23 {
+		t.Errorf("Expected rootCmd to have 23 subcommands, but got %d", len(rootCmd.Commands()))
 	}
 }
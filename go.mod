@@ -2,7 +2,11 @@ module github.com/nicobistolfi/mockthis-cli
 
 go 1.23.0
 
-require github.com/spf13/cobra v1.8.1
+require (
+	github.com/fsnotify/fsnotify v1.10.1
+	github.com/pmezard/go-difflib v1.0.0
+	github.com/spf13/cobra v1.8.1
+)
 
 require (
 	github.com/brianvoe/gofakeit/v6 v6.28.0 // indirect
@@ -11,16 +15,13 @@ require (
 	github.com/hbollon/go-edlib v1.6.0 // indirect
 	github.com/miekg/dns v1.1.62 // indirect
 	github.com/mocktools/go-smtp-mock/v2 v2.3.1 // indirect
-	github.com/pmezard/go-difflib v1.0.0 // indirect
 	github.com/stretchr/objx v0.5.2 // indirect
 	github.com/xeipuuv/gojsonpointer v0.0.0-20180127040702-4e3ac2762d5f // indirect
 	github.com/xeipuuv/gojsonreference v0.0.0-20180127040603-bd5ef7bd5415 // indirect
 	golang.org/x/mod v0.20.0 // indirect
 	golang.org/x/net v0.29.0 // indirect
 	golang.org/x/sync v0.8.0 // indirect
-	golang.org/x/sys v0.25.0 // indirect
 	golang.org/x/tools v0.24.0 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
 
 require (
@@ -30,6 +31,8 @@ require (
 	github.com/stretchr/testify v1.9.0
 	github.com/truemail-rb/truemail-go v1.1.4
 	github.com/xeipuuv/gojsonschema v1.2.0
+	golang.org/x/sys v0.25.0
 	golang.org/x/text v0.18.0
 	gopkg.in/yaml.v2 v2.4.0
+	gopkg.in/yaml.v3 v3.0.1
 )
@@ -0,0 +1,46 @@
+package commands
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/nicobistolfi/mockthis-cli/internal/config"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeAPI points the commands at handler, logged in with a config in a temporary home
+func fakeAPI(t *testing.T, handler http.Handler) *config.Data {
+	t.Helper()
+	api := httptest.NewServer(handler)
+	t.Cleanup(api.Close)
+
+	baseURL := config.BaseURL
+	config.BaseURL = api.URL
+	t.Cleanup(func() { config.BaseURL = baseURL })
+
+	t.Setenv("HOME", t.TempDir())
+	configData := &config.Data{Token: "token", Email: "user@example.com"}
+	assert.NoError(t, config.SaveConfig(config.TokenFile, configData))
+	return configData
+}
+
+func TestAPIRequest(t *testing.T) {
+	fakeAPI(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+		switch r.URL.Path {
+		case "/projects":
+			assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+			_, _ = w.Write([]byte(`{"id": "p1"}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+
+	var project map[string]interface{}
+	assert.NoError(t, apiRequest("token", http.MethodPost, "/projects", map[string]string{"name": "payments"}, &project))
+	assert.Equal(t, "p1", project["id"])
+
+	err := apiRequest("token", http.MethodGet, "/missing", nil, nil)
+	assert.EqualError(t, err, "status 404 Not Found")
+}
@@ -0,0 +1,274 @@
+package commands
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+
+	"github.com/nicobistolfi/mockthis-cli/internal/config"
+	"github.com/nicobistolfi/mockthis-cli/internal/utils"
+	"github.com/spf13/cobra"
+)
+
+// BackupCmd is the command to back up every endpoint of the account
+var BackupCmd = &cobra.Command{
+	Use:   "backup [-o <file>]",
+	Short: "Back up every endpoint of the account to an archive",
+	Long: `Back up every endpoint of the account, in every project, to a .tar.gz archive holding an
+endpoint file per endpoint and a manifest of the endpoints and projects. 'mockthis restore'
+recreates them, in the same account or in another profile.`,
+	Example: `  mockthis backup -o backup.tar.gz
+  mockthis restore backup.tar.gz --profile production`,
+	Args: cobra.NoArgs,
+	Run:  backup,
+}
+
+// backupManifestFile is the name of the manifest in backup archives
+const backupManifestFile = "manifest.json"
+
+// backupManifestVersion is the version of the backup archive format
+const backupManifestVersion = 1
+
+// maxBackupFileSize bounds the files read from backup archives, room for an endpoint file
+// holding a body of maxBodySize and the other fields of the endpoint
+const maxBackupFileSize = maxBodySize * 2
+
+// backupManifest describes the content of a backup archive
+type backupManifest struct {
+	Version   int              `json:"version"`
+	CreatedAt string           `json:"createdAt"`
+	Account   string           `json:"account"`
+	Projects  []backupProject  `json:"projects"`
+	Endpoints []backupEndpoint `json:"endpoints"`
+}
+
+type backupProject struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+}
+
+type backupEndpoint struct {
+	ID string `json:"id"`
+	// File is the path of the endpoint file in the archive
+	File      string `json:"file"`
+	ProjectID string `json:"projectId,omitempty"`
+	MockURL   string `json:"mockUrl,omitempty"`
+	CreatedAt string `json:"createdAt,omitempty"`
+}
+
+func init() {
+	BackupCmd.Flags().StringP("output", "o", "", "Path of the archive, defaults to mockthis-backup-<time>.tar.gz")
+}
+
+func backup(cmd *cobra.Command, args []string) {
+	configData, err := config.LoadConfig(config.TokenFile)
+	if err != nil {
+		fmt.Println("You need to login first.")
+		return
+	}
+	now := time.Now().UTC()
+	output, _ := cmd.Flags().GetString("output")
+	if output == "" {
+		output = "mockthis-backup-" + now.Format("20060102-150405") + ".tar.gz"
+	}
+
+	projects, err := fetchProjects(configData.Token)
+	if err != nil {
+		fmt.Println("Error fetching projects:", err)
+		os.Exit(1)
+	}
+	endpoints, err := fetchEndpoints(configData.Token, "")
+	if err != nil {
+		fmt.Println("Error fetching endpoints:", err)
+		os.Exit(1)
+	}
+
+	manifest, files, failures := buildBackup(configData.Email, projects, endpoints, now)
+	if err := writeBackup(output, manifest, files); err != nil {
+		fmt.Println("Error writing backup:", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Backed up %d endpoints and %d projects to %s\n", len(manifest.Endpoints), len(manifest.Projects), output)
+	if len(failures) > 0 {
+		printBulkSummary(os.Stdout, "Backed up", len(endpoints), failures)
+		os.Exit(1)
+	}
+}
+
+// buildBackup maps the endpoints to endpoint files, returning the manifest, the files by
+// path in the archive and the endpoints that couldn't be mapped
+func buildBackup(account string, projects, endpoints []map[string]interface{}, now time.Time) (*backupManifest, map[string][]byte, []bulkFailure) {
+	manifest := &backupManifest{
+		Version:   backupManifestVersion,
+		CreatedAt: now.Format(time.RFC3339),
+		Account:   account,
+		Projects:  []backupProject{},
+		Endpoints: []backupEndpoint{},
+	}
+	for _, project := range projects {
+		manifest.Projects = append(manifest.Projects, backupProject{
+			ID:          stringField(project, "id"),
+			Name:        stringField(project, "name"),
+			Description: stringField(project, "description"),
+		})
+	}
+
+	files := make(map[string][]byte)
+	var failures []bulkFailure
+	for _, endpoint := range endpoints {
+		id := stringField(endpoint, "id")
+		content, err := endpointFileContent(endpoint)
+		if err != nil {
+			failures = append(failures, bulkFailure{ID: id, Err: err})
+			continue
+		}
+
+		file := path.Join("endpoints", unsafeFileChars.ReplaceAllString(id, "-")+".yml")
+		files[file] = content
+		manifest.Endpoints = append(manifest.Endpoints, backupEndpoint{
+			ID:        id,
+			File:      file,
+			ProjectID: stringField(endpoint, "projectId"),
+			MockURL:   stringField(endpoint, "endpointUrl"),
+			CreatedAt: stringField(endpoint, "createdAt"),
+		})
+	}
+	return manifest, files, failures
+}
+
+// endpointFileContent writes an endpoint returned by the API as a YAML endpoint file. Text
+// looking like ${NAME} is escaped, for the file to be read back without taking it for variables.
+func endpointFileContent(endpoint map[string]interface{}) ([]byte, error) {
+	data, err := endpointFileFromAPI(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	content, err := utils.ToYAML(escapeVariables(data))
+	if err != nil {
+		return nil, err
+	}
+	return []byte(content), nil
+}
+
+// writeBackup writes the archive to a temporary file renamed to path once complete, so a
+// failed backup never replaces a previous one
+func writeBackup(filePath string, manifest *backupManifest, files map[string][]byte) error {
+	if dir := filepath.Dir(filePath); dir != "." {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(filePath), ".mockthis-backup-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := writeBackupArchive(tmp, manifest, files); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), filePath)
+}
+
+func writeBackupArchive(w io.Writer, manifest *backupManifest, files map[string][]byte) error {
+	gz := gzip.NewWriter(w)
+	archive := tar.NewWriter(gz)
+	modTime, _ := time.Parse(time.RFC3339, manifest.CreatedAt)
+
+	add := func(name string, content []byte) error {
+		header := &tar.Header{Name: name, Mode: 0644, Size: int64(len(content)), ModTime: modTime}
+		if err := archive.WriteHeader(header); err != nil {
+			return err
+		}
+		_, err := archive.Write(content)
+		return err
+	}
+
+	manifestContent, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := add(backupManifestFile, append(manifestContent, '\n')); err != nil {
+		return err
+	}
+	// Files are written in the order of the manifest, for archives to be reproducible
+	for _, endpoint := range manifest.Endpoints {
+		if err := add(endpoint.File, files[endpoint.File]); err != nil {
+			return err
+		}
+	}
+
+	if err := archive.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// readBackup reads the manifest and the files of a backup archive
+func readBackup(filePath string) (*backupManifest, map[string][]byte, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s is not a backup archive: %v", filePath, err)
+	}
+	archive := tar.NewReader(gz)
+
+	files := make(map[string][]byte)
+	for {
+		header, err := archive.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("error reading %s: %v", filePath, err)
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		// Larger files are refused rather than cut short into a different endpoint
+		if header.Size > maxBackupFileSize {
+			return nil, nil, fmt.Errorf("%s: %s is %d bytes, more than the %d bytes a backup file can have", filePath, header.Name, header.Size, maxBackupFileSize)
+		}
+		content, err := io.ReadAll(archive)
+		if err != nil {
+			return nil, nil, err
+		}
+		files[path.Clean(header.Name)] = content
+	}
+
+	manifestContent, ok := files[backupManifestFile]
+	if !ok {
+		return nil, nil, fmt.Errorf("%s has no %s", filePath, backupManifestFile)
+	}
+	delete(files, backupManifestFile)
+	var manifest backupManifest
+	if err := json.Unmarshal(manifestContent, &manifest); err != nil {
+		return nil, nil, fmt.Errorf("invalid %s: %v", backupManifestFile, err)
+	}
+	if manifest.Version != backupManifestVersion {
+		return nil, nil, fmt.Errorf("unsupported backup version %d, this version of mockthis reads version %d", manifest.Version, backupManifestVersion)
+	}
+	for _, endpoint := range manifest.Endpoints {
+		if _, ok := files[path.Clean(endpoint.File)]; !ok {
+			return nil, nil, fmt.Errorf("%s is missing the file %s of endpoint %s", filePath, endpoint.File, endpoint.ID)
+		}
+	}
+	return &manifest, files, nil
+}
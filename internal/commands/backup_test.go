@@ -0,0 +1,209 @@
+package commands
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+)
+
+// apiEndpoint is an endpoint as the list response returns it
+func apiEndpoint() map[string]interface{} {
+	var endpoint map[string]interface{}
+	_ = json.Unmarshal([]byte(`{
+		"id": "e1",
+		"projectId": "p1",
+		"endpointUrl": "https://mock.example.com/e1",
+		"createdAt": "2026-03-01T12:00:00Z",
+		"method": "post",
+		"status": 201,
+		"path": "/charges/{id}",
+		"labels": {"team": "payments"},
+		"responseContentType": "application/json",
+		"charset": "UTF-8",
+		"httpHeaders": "{\"X-Request-Id\": \"42\"}",
+		"responseBody": "{\"id\": \"ch_1\"}",
+		"responseBodySchema": "{\"type\": \"object\", \"required\": [\"id\"]}",
+		"requestContentType": "application/json",
+		"authCredentials": {"type": "oauth2", "accessToken": "secret", "expiresIn": 3600, "refreshToken": null},
+		"cors": {"origins": ["*"], "maxAge": 600}
+	}`), &endpoint)
+	return endpoint
+}
+
+func TestEndpointFileFromAPI(t *testing.T) {
+	data, err := endpointFileFromAPI(apiEndpoint())
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"apiVersion": currentAPIVersion,
+		"endpoint": map[string]interface{}{
+			"method": "POST",
+			"path":   "/charges/{id}",
+			"labels": map[string]interface{}{"team": "payments"},
+			"response": map[string]interface{}{
+				"status":       201,
+				"content-type": "application/json",
+				"charset":      "UTF-8",
+				"headers":      map[string]interface{}{"X-Request-Id": "42"},
+				"body":         `{"id": "ch_1"}`,
+				"schema":       map[string]interface{}{"type": "object", "required": []interface{}{"id"}},
+			},
+			"request": map[string]interface{}{"content-type": "application/json"},
+			"auth":    map[string]interface{}{"type": "oauth2", "accessToken": "secret", "expiresIn": 3600},
+			"cors":    map[string]interface{}{"origins": []interface{}{"*"}, "max-age": 600},
+		},
+	}, data)
+
+	// Base64 bodies are kept encoded
+	endpoint := apiEndpoint()
+	endpoint["responseBody"] = "iVBORw0KGgo="
+	endpoint["responseBodyEncoding"] = "base64"
+	data, err = endpointFileFromAPI(endpoint)
+	assert.NoError(t, err)
+	response := data["endpoint"].(map[string]interface{})["response"].(map[string]interface{})
+	assert.Equal(t, "iVBORw0KGgo=", response["body_base64"])
+	assert.NotContains(t, response, "body")
+}
+
+func TestBackupRoundTrip(t *testing.T) {
+	projects := []map[string]interface{}{{"id": "p1", "name": "payments"}}
+	broken := map[string]interface{}{"id": "e2", "responseBodySchema": "not json"}
+	manifest, files, failures := buildBackup("user@example.com", projects, []map[string]interface{}{apiEndpoint(), broken}, time.Now())
+	if assert.Len(t, failures, 1) {
+		assert.Equal(t, "e2", failures[0].ID)
+	}
+
+	archive := filepath.Join(t.TempDir(), "backups", "backup.tar.gz")
+	assert.NoError(t, writeBackup(archive, manifest, files))
+
+	read, readFiles, err := readBackup(archive)
+	assert.NoError(t, err)
+	assert.Equal(t, manifest, read)
+	assert.Equal(t, files, readFiles)
+
+	// The restored payload is the one the endpoint was created with
+	payloads, _, failures := restorePayloads(read, readFiles)
+	assert.Empty(t, failures)
+	payload := payloads["e1"]
+	assert.Equal(t, "POST", payload["method"])
+	assert.Equal(t, 201, payload["status"])
+	assert.Equal(t, "/charges/{id}", payload["path"])
+	assert.Equal(t, map[string]string{"team": "payments"}, payload["labels"])
+	assert.Equal(t, `{"id": "ch_1"}`, payload["responseBody"])
+	assert.JSONEq(t, `{"X-Request-Id": "42"}`, payload["httpHeaders"].(string))
+	assert.JSONEq(t, `{"type": "object", "required": ["id"]}`, payload["responseBodySchema"].(string))
+	assert.Equal(t, "secret", payload["authCredentials"].(map[string]interface{})["accessToken"])
+	assert.Equal(t, map[string]interface{}{"origins": []interface{}{"*"}, "maxAge": float64(600)}, payload["cors"])
+}
+
+func TestBackupKeepsVariableText(t *testing.T) {
+	// Stored text looking like variables is not taken from the environment on restore
+	t.Setenv("X", "leaked")
+	endpoint := apiEndpoint()
+	endpoint["responseBody"] = `Hello ${X}, ${MISSING:-none} and $${X}`
+	endpoint["httpHeaders"] = `{"X-Template": "${X}"}`
+	manifest, files, failures := buildBackup("user@example.com", nil, []map[string]interface{}{endpoint}, time.Now())
+	assert.Empty(t, failures)
+
+	archive := filepath.Join(t.TempDir(), "backup.tar.gz")
+	assert.NoError(t, writeBackup(archive, manifest, files))
+	read, readFiles, err := readBackup(archive)
+	assert.NoError(t, err)
+	payloads, _, failures := restorePayloads(read, readFiles)
+	assert.Empty(t, failures)
+	assert.Equal(t, `Hello ${X}, ${MISSING:-none} and $${X}`, payloads["e1"]["responseBody"])
+	assert.JSONEq(t, `{"X-Template": "${X}"}`, payloads["e1"]["httpHeaders"].(string))
+}
+
+func TestRestoreExpiredEndpoints(t *testing.T) {
+	// Endpoints expired since the backup are restored without their expiry
+	expired, expiring := apiEndpoint(), apiEndpoint()
+	expired["expiresAt"] = "2000-01-01T00:00:00Z"
+	expiring["id"], expiring["path"], expiring["expiresAt"] = "e2", "/refunds", "2099-01-01T00:00:00Z"
+	manifest, files, failures := buildBackup("user@example.com", nil, []map[string]interface{}{expired, expiring}, time.Now())
+	assert.Empty(t, failures)
+
+	payloads, dropped, failures := restorePayloads(manifest, files)
+	assert.Empty(t, failures)
+	assert.Equal(t, map[string]string{"e1": "2000-01-01T00:00:00Z"}, dropped)
+	assert.NotContains(t, payloads["e1"], "expiresAt")
+	assert.Equal(t, "2099-01-01T00:00:00Z", payloads["e2"]["expiresAt"])
+}
+
+func TestReadBackupFileTooLarge(t *testing.T) {
+	manifest, files, failures := buildBackup("user@example.com", nil, []map[string]interface{}{apiEndpoint()}, time.Now())
+	assert.Empty(t, failures)
+	large := manifest.Endpoints[0].File
+	files[large] = bytes.Repeat([]byte("a"), maxBackupFileSize+1)
+
+	archive := filepath.Join(t.TempDir(), "backup.tar.gz")
+	assert.NoError(t, writeBackup(archive, manifest, files))
+	_, _, err := readBackup(archive)
+	assert.EqualError(t, err, fmt.Sprintf("%s: %s is %d bytes, more than the %d bytes a backup file can have", archive, large, maxBackupFileSize+1, maxBackupFileSize))
+}
+
+func TestRestorePayloadsFailures(t *testing.T) {
+	manifest, files, failures := buildBackup("user@example.com", nil, []map[string]interface{}{apiEndpoint()}, time.Now())
+	assert.Empty(t, failures)
+
+	// Files that can't be read again fail their endpoint only
+	manifest.Endpoints = append(manifest.Endpoints, backupEndpoint{ID: "e2", File: "endpoints/e2.yml"})
+	files["endpoints/e2.yml"] = []byte("endpoint: [")
+	payloads, _, failures := restorePayloads(manifest, files)
+	assert.Contains(t, payloads, "e1")
+	if assert.Len(t, failures, 1) {
+		assert.Equal(t, "e2", failures[0].ID)
+	}
+
+	// Without a temporary directory, every endpoint fails instead of the command exiting
+	t.Setenv("TMPDIR", filepath.Join(t.TempDir(), "missing"))
+	payloads, _, failures = restorePayloads(manifest, files)
+	assert.Empty(t, payloads)
+	if assert.Len(t, failures, 2) {
+		assert.Contains(t, failures[1].Err.Error(), "creating a temporary directory")
+	}
+}
+
+func TestRestoreProjects(t *testing.T) {
+	var created []string
+	configData := fakeAPI(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method + " " + r.URL.Path {
+		case "GET /projects":
+			_, _ = w.Write([]byte(`[{"id": "q1", "name": "payments"}]`))
+		case "POST /projects":
+			var payload map[string]interface{}
+			_ = json.NewDecoder(r.Body).Decode(&payload)
+			created = append(created, payload["name"].(string))
+			_, _ = w.Write([]byte(`{"id": "q2"}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+
+	manifest := &backupManifest{
+		Projects:  []backupProject{{ID: "p1", Name: "payments"}, {ID: "p2", Name: "search"}, {ID: "p3", Name: "unused"}},
+		Endpoints: []backupEndpoint{{ID: "e1", ProjectID: "p1"}, {ID: "e2", ProjectID: "p2"}, {ID: "e3"}},
+	}
+
+	cmd := &cobra.Command{}
+	cmd.Flags().String("project", "", "")
+	projects, err := restoreProjects(cmd, configData, manifest)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]backupProject{
+		"p1": {ID: "q1", Name: "payments"},
+		"p2": {ID: "q2", Name: "search"},
+	}, projects)
+	assert.Equal(t, []string{"search"}, created)
+
+	assert.NoError(t, cmd.Flags().Set("project", "payments"))
+	projects, err = restoreProjects(cmd, configData, manifest)
+	assert.NoError(t, err)
+	assert.Equal(t, backupProject{ID: "q1", Name: "payments"}, projects[""])
+	assert.Equal(t, backupProject{ID: "q1", Name: "payments"}, projects["p2"])
+}
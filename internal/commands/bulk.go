@@ -0,0 +1,262 @@
+package commands
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nicobistolfi/mockthis-cli/internal/config"
+	"github.com/spf13/cobra"
+)
+
+// bulkFailure is an item a bulk operation failed on
+type bulkFailure struct {
+	ID  string
+	Err error
+}
+
+// bulkOptions bounds the concurrency and rate of bulk operations
+type bulkOptions struct {
+	// Workers is the number of operations run at the same time
+	Workers int
+	// Rate is the number of operations started per second, unlimited when 0
+	Rate float64
+}
+
+// addBulkFlags adds the flags of bulkOptions to a command
+func addBulkFlags(cmd *cobra.Command) {
+	cmd.Flags().Int("concurrency", 8, "Number of requests sent at the same time")
+	cmd.Flags().Float64("rate", 10, "Maximum number of requests per second, 0 for no limit")
+}
+
+// bulkFlags returns the options set by the flags of addBulkFlags
+func bulkFlags(cmd *cobra.Command) (bulkOptions, error) {
+	workers, _ := cmd.Flags().GetInt("concurrency")
+	rate, _ := cmd.Flags().GetFloat64("rate")
+	if workers < 1 {
+		return bulkOptions{}, fmt.Errorf("--concurrency must be at least 1")
+	}
+	if rate < 0 {
+		return bulkOptions{}, fmt.Errorf("--rate can't be negative")
+	}
+	return bulkOptions{Workers: workers, Rate: rate}, nil
+}
+
+// addSelectionFlags adds the flags selecting the endpoints of a bulk command besides the IDs
+// given as arguments. verb is what the command does to them, eg. "delete", and selectorShort
+// the shorthand of --selector, if any.
+func addSelectionFlags(cmd *cobra.Command, verb, selectorShort string) {
+	cmd.Flags().StringP("selector", selectorShort, "", "Label selector of the endpoints to "+verb+". Eg. 'team=payments,env=ci'")
+	cmd.Flags().Bool("all", false, "Select all the endpoints of the project")
+	cmd.Flags().BoolP("all-projects", "A", false, "Select the endpoints of every project with --all or a selector")
+	addProjectFlag(cmd)
+}
+
+// bulkIDs returns the IDs of the endpoints a bulk command works on: those given as arguments,
+// read from stdin for -, or else selected with the flags of addSelectionFlags
+func bulkIDs(cmd *cobra.Command, configData *config.Data, args []string) ([]string, error) {
+	selector, _ := cmd.Flags().GetString("selector")
+	all, _ := cmd.Flags().GetBool("all")
+	allProjects, _ := cmd.Flags().GetBool("all-projects")
+
+	selectorFlag := "--selector"
+	if flag := cmd.Flags().Lookup("selector"); flag.Shorthand != "" {
+		selectorFlag = "-" + flag.Shorthand
+	}
+	sources := 0
+	for _, given := range []bool{len(args) > 0, selector != "", all} {
+		if given {
+			sources++
+		}
+	}
+	if sources != 1 {
+		return nil, fmt.Errorf("give endpoint IDs, - to read them from stdin, --all or a label selector with %s", selectorFlag)
+	}
+	if len(args) > 0 {
+		if allProjects {
+			return nil, fmt.Errorf("--all-projects selects endpoints with --all or %s, not endpoints given by ID", selectorFlag)
+		}
+		return endpointArguments(args)
+	}
+	return selectedIDs(cmd, configData, selector, all)
+}
+
+// endpointArguments returns the IDs given as arguments, resolving endpoint files and names,
+// and reading them from stdin for -
+func endpointArguments(args []string) ([]string, error) {
+	var ids []string
+	for _, arg := range args {
+		if arg != "-" {
+			id, err := resolveEndpointRef(arg)
+			if err != nil {
+				return nil, err
+			}
+			ids = append(ids, id)
+			continue
+		}
+		read, err := readIDs(os.Stdin)
+		if err != nil {
+			return nil, fmt.Errorf("error reading IDs: %w", err)
+		}
+		ids = append(ids, read...)
+	}
+	return uniqueIDs(ids), nil
+}
+
+// selectedIDs lists the endpoints of the project matching selector, returning their IDs.
+// Selecting all the endpoints takes a project, or --all-projects for those of the account.
+func selectedIDs(cmd *cobra.Command, configData *config.Data, selector string, all bool) ([]string, error) {
+	projectID := ""
+	if allProjects, _ := cmd.Flags().GetBool("all-projects"); allProjects {
+		if cmd.Flags().Changed("project") {
+			return nil, fmt.Errorf("--project and --all-projects can't be used together")
+		}
+	} else {
+		var err error
+		if projectID, err = projectFlag(cmd, configData); err != nil {
+			return nil, fmt.Errorf("error finding project: %w", err)
+		}
+		if all && projectID == "" {
+			return nil, fmt.Errorf("there is no current project: give one with --project, or use --all-projects to select the endpoints of every project")
+		}
+	}
+	endpoints, err := fetchEndpoints(configData.Token, projectID)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching endpoints: %w", err)
+	}
+	if endpoints, err = selectEndpoints(endpoints, selector); err != nil {
+		return nil, fmt.Errorf("invalid selector: %w", err)
+	}
+
+	ids := make([]string, 0, len(endpoints))
+	for _, endpoint := range endpoints {
+		fmt.Printf("  %v %v\n", endpoint["id"], endpoint["endpointUrl"])
+		id, _ := endpoint["id"].(string)
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// uniqueIDs removes repeated IDs, keeping the first occurrence
+func uniqueIDs(ids []string) []string {
+	seen := make(map[string]bool, len(ids))
+	unique := ids[:0]
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	return unique
+}
+
+// runBulk runs op on every ID through a pool of workers, writing the progress to progress
+// when it is not nil, and returns the failures in the order of ids
+func runBulk(ids []string, options bulkOptions, progress io.Writer, op func(id string) error) []bulkFailure {
+	workers := options.Workers
+	if workers > len(ids) {
+		workers = len(ids)
+	}
+
+	// Rates too high for an interval of a nanosecond are as good as no limit
+	var tick <-chan time.Time
+	if options.Rate > 0 && options.Rate <= float64(time.Second) {
+		ticker := time.NewTicker(time.Duration(float64(time.Second) / options.Rate))
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	indexes := make(chan int)
+	var (
+		mu       sync.Mutex
+		done     int
+		failures []bulkFailure
+		order    = make(map[string]int, len(ids))
+		wg       sync.WaitGroup
+	)
+	for i, id := range ids {
+		order[id] = i
+	}
+
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				err := op(ids[i])
+
+				mu.Lock()
+				done++
+				if err != nil {
+					failures = append(failures, bulkFailure{ID: ids[i], Err: err})
+				}
+				if progress != nil {
+					fmt.Fprintf(progress, "\r%d/%d done, %d failed", done, len(ids), len(failures))
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+	for i := range ids {
+		// The first operation starts right away, the others wait for the rate limit
+		if tick != nil && i > 0 {
+			<-tick
+		}
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	if progress != nil && len(ids) > 0 {
+		fmt.Fprintln(progress)
+	}
+	sort.Slice(failures, func(i, j int) bool { return order[failures[i].ID] < order[failures[j].ID] })
+	return failures
+}
+
+// printBulkSummary reports the outcome of a bulk operation. verb is the past participle of
+// the operation, eg. "Deleted".
+func printBulkSummary(w io.Writer, verb string, total int, failures []bulkFailure) {
+	fmt.Fprintf(w, "%s %d of %d endpoints\n", verb, total-len(failures), total)
+	if len(failures) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "%d failed:\n", len(failures))
+	for _, failure := range failures {
+		fmt.Fprintf(w, "  %s: %v\n", failure.ID, failure.Err)
+	}
+}
+
+// progressWriter returns stderr when it is a terminal, where the progress line is redrawn in
+// place, and nil otherwise to keep logs free of it
+func progressWriter() io.Writer {
+	if !isTerminal(os.Stderr) {
+		return nil
+	}
+	return os.Stderr
+}
+
+// readIDs reads whitespace separated IDs, one or more per line
+func readIDs(r io.Reader) ([]string, error) {
+	var ids []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		ids = append(ids, strings.Fields(scanner.Text())...)
+	}
+	return ids, scanner.Err()
+}
+
+// deleteEndpoints deletes endpoints by ID through runBulk
+func deleteEndpoints(token string, ids []string, options bulkOptions) []bulkFailure {
+	return runBulk(ids, options, progressWriter(), func(id string) error {
+		return apiRequest(token, http.MethodDelete, "/endpoints/"+url.PathEscape(id), nil, nil)
+	})
+}
@@ -0,0 +1,74 @@
+package commands
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunBulk(t *testing.T) {
+	ids := make([]string, 20)
+	for i := range ids {
+		ids[i] = fmt.Sprintf("e%d", i)
+	}
+
+	var running, maxRunning int32
+	var mu sync.Mutex
+	var seen []string
+	failures := runBulk(ids, bulkOptions{Workers: 3}, nil, func(id string) error {
+		n := atomic.AddInt32(&running, 1)
+		defer atomic.AddInt32(&running, -1)
+		mu.Lock()
+		if n > maxRunning {
+			maxRunning = n
+		}
+		seen = append(seen, id)
+		mu.Unlock()
+		time.Sleep(5 * time.Millisecond)
+		if id == "e3" || id == "e17" {
+			return errors.New("status 500 Internal Server Error")
+		}
+		return nil
+	})
+
+	assert.ElementsMatch(t, ids, seen)
+	assert.LessOrEqual(t, maxRunning, int32(3))
+	if assert.Len(t, failures, 2) {
+		assert.Equal(t, "e3", failures[0].ID)
+		assert.Equal(t, "e17", failures[1].ID)
+	}
+
+	var summary bytes.Buffer
+	printBulkSummary(&summary, "Deleted", len(ids), failures)
+	assert.Equal(t, "Deleted 18 of 20 endpoints\n2 failed:\n  e3: status 500 Internal Server Error\n  e17: status 500 Internal Server Error\n", summary.String())
+}
+
+func TestRunBulkRate(t *testing.T) {
+	start := time.Now()
+	var progress bytes.Buffer
+	failures := runBulk([]string{"a", "b", "c", "d", "e"}, bulkOptions{Workers: 5, Rate: 50}, &progress, func(string) error { return nil })
+	assert.Empty(t, failures)
+	// Four waits of 20ms after the first operation
+	assert.GreaterOrEqual(t, time.Since(start), 80*time.Millisecond)
+	assert.Contains(t, progress.String(), "5/5 done, 0 failed")
+}
+
+func TestRunBulkHighRate(t *testing.T) {
+	// The interval between operations rounds to 0, which a ticker can't have
+	failures := runBulk([]string{"a", "b"}, bulkOptions{Workers: 2, Rate: 2e9}, nil, func(string) error { return nil })
+	assert.Empty(t, failures)
+}
+
+func TestReadIDs(t *testing.T) {
+	ids, err := readIDs(strings.NewReader("e1\n e2 e3\n\ne1\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"e1", "e2", "e3", "e1"}, ids)
+	assert.Equal(t, []string{"e1", "e2", "e3"}, uniqueIDs(ids))
+}
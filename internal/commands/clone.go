@@ -0,0 +1,170 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/nicobistolfi/mockthis-cli/internal/config"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// CloneCmd is the command to create a copy of an endpoint with some fields changed
+var CloneCmd = &cobra.Command{
+	Use:   "clone <id, mockIdentifier, name or endpoint file> [flags]",
+	Short: "Create a copy of an endpoint, changing the fields given with flags",
+	Long: `Create a new endpoint from an existing one, changing the fields given with the same flags
+as create. Labels are added to those of the endpoint, and a body given with --body,
+--body-file or --body-base64 replaces the body of the endpoint.
+
+The copy goes to the project of the endpoint, or to the one given with --project.`,
+	Example: `  mockthis clone 1a2b3c --status 500 --body '{"error":"boom"}'
+  mockthis clone mocks/charges --status 429 --headers 'Retry-After: 30' --label variant=throttled`,
+	Args: cobra.ExactArgs(1),
+	Run:  cloneEndpoint,
+}
+
+// bodyFlags are the flags giving the response body, only one of which can be set
+var bodyFlags = []string{"body", "body-file", "body-base64"}
+
+func init() {
+	addEndpointFieldFlags(CloneCmd)
+	addCreatedOutputFlag(CloneCmd)
+}
+
+func cloneEndpoint(cmd *cobra.Command, args []string) {
+	configData, err := config.LoadConfig(config.TokenFile)
+	if err != nil {
+		fmt.Println("You need to login first.")
+		return
+	}
+
+	id, err := resolveEndpointRef(args[0])
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	endpoints, err := fetchEndpoints(configData.Token, "")
+	if err != nil {
+		fmt.Println("Error fetching endpoints:", err)
+		os.Exit(1)
+	}
+	source := findEndpoint(endpoints, id)
+	if source == nil {
+		fmt.Println("Endpoint not found.")
+		os.Exit(1)
+	}
+
+	endpointData, err := clonePayload(source, cmd.Flags())
+	if err != nil {
+		fmt.Println("Error cloning endpoint:", err)
+		os.Exit(1)
+	}
+	if expiresAt := stringField(source, "expiresAt"); pastExpiry(expiresAt) && !cmd.Flags().Changed("ttl") && !cmd.Flags().Changed("expires-at") {
+		fmt.Fprintf(os.Stderr, "The endpoint expired at %s, the copy doesn't expire\n", expiresAt)
+	}
+	projectID := stringField(source, "projectId")
+	if cmd.Flags().Changed("project") {
+		if projectID, err = projectFlag(cmd, configData); err != nil {
+			fmt.Println("Error finding project:", err)
+			os.Exit(1)
+		}
+	}
+	if projectID != "" {
+		endpointData["projectId"] = projectID
+	}
+
+	response := queryAPIEndpoint(endpointData)
+	created, err := decodeCreateResponse(response)
+	if err != nil {
+		fmt.Println("Error processing API response:", err)
+		os.Exit(1)
+	}
+	printCreated(cmd, created)
+}
+
+// clonePayload builds the create payload of a copy of an endpoint of the API, with the
+// fields set by the changed flags of overrides. The endpoint is read as its endpoint file,
+// the way `create --file` reads files, for the flags to map to the same fields. The file
+// escapes text such as ${NAME}, which the copy keeps as it is.
+func clonePayload(endpoint map[string]interface{}, overrides *pflag.FlagSet) (map[string]interface{}, error) {
+	content, err := endpointFileContent(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	dir, err := os.MkdirTemp("", "mockthis-clone-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+	filePath := filepath.Join(dir, "endpoint.yml")
+	if err := os.WriteFile(filePath, content, 0644); err != nil {
+		return nil, err
+	}
+
+	cmd := &cobra.Command{}
+	addEndpointFlags(cmd)
+
+	// Labels and expiry flags are set before reading the file, which gives way to them
+	err = setChangedFlags(cmd.Flags(), overrides, func(name string) bool {
+		return name == "label" || name == "ttl" || name == "expires-at"
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := loadFromFile(filePath, cmd); err != nil {
+		return nil, err
+	}
+	// The copy of an expired endpoint doesn't expire, unless given an expiry
+	if !overrides.Changed("expires-at") {
+		dropPastExpiry(cmd)
+	}
+
+	// The other flags replace the fields of the file, a new body replacing the body of the
+	// file whichever way it is given
+	for _, name := range bodyFlags {
+		if overrides.Changed(name) {
+			for _, other := range bodyFlags {
+				resetFlag(cmd.Flags(), other)
+			}
+		}
+	}
+	err = setChangedFlags(cmd.Flags(), overrides, func(name string) bool {
+		return name != "label" && name != "ttl" && name != "expires-at" && name != "project"
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return endpointPayload(cmd)
+}
+
+// setChangedFlags sets the flags changed in from and accepted by include on flags, skipping
+// those flags doesn't have, such as persistent flags of the root command
+func setChangedFlags(flags, from *pflag.FlagSet, include func(name string) bool) error {
+	var err error
+	from.Visit(func(flag *pflag.Flag) {
+		if err != nil || !include(flag.Name) || flags.Lookup(flag.Name) == nil {
+			return
+		}
+		if slice, ok := flag.Value.(pflag.SliceValue); ok {
+			for _, value := range slice.GetSlice() {
+				if err = flags.Set(flag.Name, value); err != nil {
+					return
+				}
+			}
+			return
+		}
+		err = flags.Set(flag.Name, flag.Value.String())
+	})
+	return err
+}
+
+// resetFlag sets a flag back to its default value, as if it wasn't given
+func resetFlag(flags *pflag.FlagSet, name string) {
+	if flag := flags.Lookup(name); flag != nil && flag.Changed {
+		_ = flag.Value.Set(flag.DefValue)
+		flag.Changed = false
+	}
+}
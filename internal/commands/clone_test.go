@@ -0,0 +1,92 @@
+package commands
+
+import (
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+)
+
+// cloneFlags parses args with the flags of clone
+func cloneFlags(t *testing.T, args ...string) *cobra.Command {
+	cmd := &cobra.Command{}
+	addEndpointFieldFlags(cmd)
+	assert.NoError(t, cmd.Flags().Parse(args))
+	return cmd
+}
+
+func TestClonePayload(t *testing.T) {
+	cmd := cloneFlags(t, "--status", "500", "--body", `{"error":"boom"}`, "--label", "variant=error")
+	payload, err := clonePayload(apiEndpoint(), cmd.Flags())
+	assert.NoError(t, err)
+
+	// Changed fields
+	assert.Equal(t, 500, payload["status"])
+	assert.Equal(t, `{"error":"boom"}`, payload["responseBody"])
+	assert.Equal(t, map[string]string{"team": "payments", "variant": "error"}, payload["labels"])
+
+	// Copied fields
+	assert.Equal(t, "POST", payload["method"])
+	assert.Equal(t, "/charges/{id}", payload["path"])
+	assert.Equal(t, "application/json", payload["responseContentType"])
+	assert.JSONEq(t, `{"X-Request-Id": "42"}`, payload["httpHeaders"].(string))
+	assert.JSONEq(t, `{"type": "object", "required": ["id"]}`, payload["responseBodySchema"].(string))
+	assert.NotNil(t, payload["authCredentials"])
+	assert.NotNil(t, payload["cors"])
+}
+
+func TestClonePayloadWithoutOverrides(t *testing.T) {
+	payload, err := clonePayload(apiEndpoint(), cloneFlags(t).Flags())
+	assert.NoError(t, err)
+	assert.Equal(t, 201, payload["status"])
+	assert.Equal(t, `{"id": "ch_1"}`, payload["responseBody"])
+	assert.Equal(t, map[string]string{"team": "payments"}, payload["labels"])
+}
+
+func TestClonePayloadReplacesBody(t *testing.T) {
+	// A body given another way replaces the body of the endpoint
+	payload, err := clonePayload(apiEndpoint(), cloneFlags(t, "--body-base64", "AAEC").Flags())
+	assert.NoError(t, err)
+	assert.Equal(t, "AAEC", payload["responseBody"])
+	assert.Equal(t, "base64", payload["responseBodyEncoding"])
+
+	endpoint := apiEndpoint()
+	endpoint["responseBody"] = "AAEC"
+	endpoint["responseBodyEncoding"] = "base64"
+	payload, err = clonePayload(endpoint, cloneFlags(t, "--body", "text").Flags())
+	assert.NoError(t, err)
+	assert.Equal(t, "text", payload["responseBody"])
+	assert.Nil(t, payload["responseBodyEncoding"])
+}
+
+func TestClonePayloadExpiry(t *testing.T) {
+	endpoint := apiEndpoint()
+	endpoint["expiresAt"] = "2000-01-01T00:00:00Z"
+
+	// The past expiry of the endpoint gives way to --ttl
+	payload, err := clonePayload(endpoint, cloneFlags(t, "--ttl", "1h").Flags())
+	assert.NoError(t, err)
+	assert.NotEqual(t, "2000-01-01T00:00:00Z", payload["expiresAt"])
+
+	// Or else is dropped, rather than failing the copy
+	payload, err = clonePayload(endpoint, cloneFlags(t).Flags())
+	assert.NoError(t, err)
+	assert.NotContains(t, payload, "expiresAt")
+
+	// A past expiry given with --expires-at still fails
+	_, err = clonePayload(endpoint, cloneFlags(t, "--expires-at", "2001-01-01T00:00:00Z").Flags())
+	assert.Error(t, err)
+}
+
+func TestClonePayloadKeepsVariableText(t *testing.T) {
+	// Text looking like variables is copied, not filled in from the environment
+	t.Setenv("X", "leaked")
+	endpoint := apiEndpoint()
+	endpoint["responseBody"] = `Hello ${X} and ${MISSING}`
+	endpoint["httpHeaders"] = `{"X-Template": "${X}"}`
+	payload, err := clonePayload(endpoint, cloneFlags(t, "--status", "500").Flags())
+	assert.NoError(t, err)
+	assert.Equal(t, `Hello ${X} and ${MISSING}`, payload["responseBody"])
+	assert.JSONEq(t, `{"X-Template": "${X}"}`, payload["httpHeaders"].(string))
+	assert.Equal(t, 500, payload["status"])
+}
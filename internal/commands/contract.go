@@ -0,0 +1,489 @@
+package commands
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/nicobistolfi/mockthis-cli/internal/server"
+	"github.com/nicobistolfi/mockthis-cli/internal/utils"
+	"github.com/spf13/cobra"
+)
+
+// contract is what an endpoint promises: the request it accepts and the response it returns
+// to that request. It is read from an endpoint file or from an endpoint stored in the API.
+type contract struct {
+	Name               string
+	Method             string
+	Target             string // Path and query for endpoint files, absolute URL for stored endpoints
+	Auth               map[string]interface{}
+	RequestContentType string
+	RequestSchema      map[string]interface{}
+	Status             int
+	ContentType        string
+	Headers            map[string]string
+	ResponseSchema     map[string]interface{}
+}
+
+// check is one verified property of a response, Err is nil when it holds
+type check struct {
+	Name string
+	Err  error
+}
+
+// contractFromFile reads the contract of an endpoint file, named relative to dir
+func contractFromFile(dir, path string, vars map[string]string) (*contract, error) {
+	data, err := readEndpointFile(path, vars)
+	if err != nil {
+		return nil, err
+	}
+	endpoint, err := server.NewEndpoint(endpointName(dir, path), data)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &contract{
+		Name:        endpoint.Name,
+		Method:      endpoint.Method,
+		Target:      endpoint.ExamplePath(),
+		Auth:        endpoint.Auth,
+		Status:      endpoint.Response.Status,
+		ContentType: endpoint.Response.ContentType,
+		Headers:     endpoint.Response.Headers,
+	}
+
+	if request, ok := data["request"].(map[string]interface{}); ok {
+		c.RequestContentType, _ = request["content-type"].(string)
+		if c.RequestSchema, err = schemaValue(request["schema"]); err != nil {
+			return nil, fmt.Errorf("request schema: %v", err)
+		}
+	}
+	if response, ok := data["response"].(map[string]interface{}); ok {
+		if c.ResponseSchema, err = schemaValue(response["schema"]); err != nil {
+			return nil, fmt.Errorf("response schema: %v", err)
+		}
+	}
+	return c, nil
+}
+
+// contractFromAPI reads the contract of an endpoint returned by the API
+func contractFromAPI(endpoint map[string]interface{}) (*contract, error) {
+	c := &contract{
+		Method:             http.MethodGet,
+		Status:             http.StatusOK,
+		Headers:            make(map[string]string),
+		RequestContentType: stringField(endpoint, "requestContentType"),
+		ContentType:        stringField(endpoint, "responseContentType"),
+		Target:             stringField(endpoint, "endpointUrl"),
+	}
+	c.Name = stringField(endpoint, "id")
+
+	if method := stringField(endpoint, "method"); method != "" {
+		c.Method = strings.ToUpper(method)
+	}
+	if status, ok := endpoint["status"].(float64); ok {
+		c.Status = int(status)
+	}
+	if auth, ok := endpoint["authCredentials"].(map[string]interface{}); ok {
+		c.Auth = auth
+	}
+
+	switch headers := endpoint["httpHeaders"].(type) {
+	case map[string]interface{}:
+		for key, value := range headers {
+			c.Headers[key] = fmt.Sprintf("%v", value)
+		}
+	case string:
+		for key, value := range parseHeaderList(headers) {
+			c.Headers[key] = value
+		}
+	}
+
+	var err error
+	if c.RequestSchema, err = schemaValue(endpoint["requestBodySchema"]); err != nil {
+		return nil, fmt.Errorf("request schema: %v", err)
+	}
+	if c.ResponseSchema, err = schemaValue(endpoint["responseBodySchema"]); err != nil {
+		return nil, fmt.Errorf("response schema: %v", err)
+	}
+	return c, nil
+}
+
+// parseHeaderList reads headers stored as JSON or as comma-separated "Key: value" pairs
+func parseHeaderList(headers string) map[string]string {
+	parsed := make(map[string]string)
+	if utils.IsJSON(headers) {
+		var data map[string]interface{}
+		if json.Unmarshal([]byte(headers), &data) == nil {
+			for key, value := range data {
+				parsed[key] = fmt.Sprintf("%v", value)
+			}
+		}
+		return parsed
+	}
+	for _, pair := range strings.Split(headers, ",") {
+		key, value, ok := strings.Cut(pair, ":")
+		if !ok {
+			key, value, ok = strings.Cut(pair, "=")
+		}
+		if ok && strings.TrimSpace(key) != "" {
+			parsed[strings.TrimSpace(key)] = strings.TrimSpace(value)
+		}
+	}
+	return parsed
+}
+
+// schemaValue reads a JSON schema given as a map or as a JSON string
+func schemaValue(value interface{}) (map[string]interface{}, error) {
+	switch schema := value.(type) {
+	case nil:
+		return nil, nil
+	case map[string]interface{}:
+		return schema, nil
+	case string:
+		if strings.TrimSpace(schema) == "" {
+			return nil, nil
+		}
+		var parsed map[string]interface{}
+		if err := json.Unmarshal([]byte(schema), &parsed); err != nil {
+			return nil, fmt.Errorf("schema is not a JSON object: %v", err)
+		}
+		return parsed, nil
+	}
+	return nil, fmt.Errorf("schema is not an object")
+}
+
+func stringField(data map[string]interface{}, key string) string {
+	value, _ := data[key].(string)
+	return value
+}
+
+// newRequest builds the request the contract accepts. Relative targets are resolved against
+// baseURL. The body is an example generated from the request schema.
+func (c *contract) newRequest(baseURL string) (*http.Request, error) {
+	target := c.Target
+	if !strings.HasPrefix(target, "http://") && !strings.HasPrefix(target, "https://") {
+		target = strings.TrimSuffix(baseURL, "/") + target
+	}
+
+	var body io.Reader
+	if c.RequestSchema != nil {
+		example, err := json.Marshal(exampleFromSchema(c.RequestSchema))
+		if err != nil {
+			return nil, err
+		}
+		body = bytes.NewReader(example)
+	}
+
+	req, err := http.NewRequest(c.Method, target, body)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		contentType := c.RequestContentType
+		if contentType == "" {
+			contentType = "application/json"
+		}
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	if err := addCredentials(req, c.Auth); err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+// addCredentials sets the credentials of an auth block, nested under "properties" or not, on
+// the request. mTLS credentials are the client certificate and are set on the client.
+func addCredentials(req *http.Request, auth map[string]interface{}) error {
+	if auth == nil {
+		return nil
+	}
+
+	switch authType, _ := auth["type"].(string); authType {
+	case "basic":
+		req.SetBasicAuth(credential(auth, "username"), credential(auth, "password"))
+	case "apiKey":
+		if credential(auth, "in") == "query" {
+			query := req.URL.Query()
+			query.Set(credential(auth, "name"), credential(auth, "value"))
+			req.URL.RawQuery = query.Encode()
+		} else {
+			req.Header.Set(credential(auth, "name"), credential(auth, "value"))
+		}
+	case "bearer", "jwt":
+		req.Header.Set("Authorization", "Bearer "+credential(auth, "token"))
+	case "oauth2":
+		tokenType := credential(auth, "tokenType")
+		if tokenType == "" {
+			tokenType = "Bearer"
+		}
+		req.Header.Set("Authorization", tokenType+" "+credential(auth, "accessToken"))
+	case "mtls":
+	default:
+		return fmt.Errorf("unsupported auth type %q", authType)
+	}
+	return nil
+}
+
+func credential(auth map[string]interface{}, key string) string {
+	if properties, ok := auth["properties"].(map[string]interface{}); ok {
+		if value, ok := properties[key]; ok && value != nil {
+			return fmt.Sprintf("%v", value)
+		}
+	}
+	if value, ok := auth[key]; ok && value != nil {
+		return fmt.Sprintf("%v", value)
+	}
+	return ""
+}
+
+// checkResponse verifies a response against the contract. With exactHeaders the headers
+// must have the promised values, otherwise they only have to be present.
+func (c *contract) checkResponse(resp *http.Response, body []byte, exactHeaders bool) []check {
+	checks := []check{{Name: fmt.Sprintf("status %d", c.Status)}}
+	if resp.StatusCode != c.Status {
+		checks[0].Err = fmt.Errorf("got %d", resp.StatusCode)
+	}
+
+	if c.ContentType != "" {
+		contentType := check{Name: "content-type " + c.ContentType}
+		if !sameMediaType(c.ContentType, resp.Header.Get("Content-Type")) {
+			contentType.Err = fmt.Errorf("got %q", resp.Header.Get("Content-Type"))
+		}
+		checks = append(checks, contentType)
+	}
+
+	names := make([]string, 0, len(c.Headers))
+	for name := range c.Headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		header := check{Name: "header " + name}
+		values := resp.Header.Values(name)
+		switch {
+		case len(values) == 0:
+			header.Err = fmt.Errorf("missing")
+		case exactHeaders && values[0] != c.Headers[name]:
+			header.Err = fmt.Errorf("got %q, want %q", values[0], c.Headers[name])
+		}
+		checks = append(checks, header)
+	}
+
+	if c.ResponseSchema != nil {
+		schema := check{Name: "body matches response schema"}
+		schema.Err = validateBody(body, c.ResponseSchema)
+		checks = append(checks, schema)
+	}
+
+	return checks
+}
+
+func sameMediaType(expected, actual string) bool {
+	want, _, err := mime.ParseMediaType(expected)
+	if err != nil {
+		want = expected
+	}
+	got, _, err := mime.ParseMediaType(actual)
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(want, got)
+}
+
+func validateBody(body []byte, schema map[string]interface{}) error {
+	var data interface{}
+	if err := json.Unmarshal(body, &data); err != nil {
+		return fmt.Errorf("body is not JSON: %v", err)
+	}
+	schemaJSON, err := json.Marshal(schema)
+	if err != nil {
+		return err
+	}
+	return utils.ValidateAgainstSchema(data, string(schemaJSON))
+}
+
+// exampleFromSchema generates a value valid against simple JSON schemas, preferring the
+// examples, defaults and enums the schema gives
+func exampleFromSchema(schema map[string]interface{}) interface{} {
+	if value, ok := schema["const"]; ok {
+		return value
+	}
+	if value, ok := schema["example"]; ok {
+		return value
+	}
+	if examples, ok := schema["examples"].([]interface{}); ok && len(examples) > 0 {
+		return examples[0]
+	}
+	if value, ok := schema["default"]; ok {
+		return value
+	}
+	if enum, ok := schema["enum"].([]interface{}); ok && len(enum) > 0 {
+		return enum[0]
+	}
+	for _, key := range []string{"allOf", "anyOf", "oneOf"} {
+		if schemas, ok := schema[key].([]interface{}); ok && len(schemas) > 0 {
+			if first, ok := schemas[0].(map[string]interface{}); ok {
+				return exampleFromSchema(first)
+			}
+		}
+	}
+
+	schemaType := schema["type"]
+	if types, ok := schemaType.([]interface{}); ok && len(types) > 0 {
+		schemaType = types[0]
+	}
+	if schemaType == nil {
+		if _, ok := schema["properties"]; ok {
+			schemaType = "object"
+		}
+	}
+
+	switch schemaType {
+	case "object":
+		example := make(map[string]interface{})
+		properties, _ := schema["properties"].(map[string]interface{})
+		for name, property := range properties {
+			if propertySchema, ok := property.(map[string]interface{}); ok {
+				example[name] = exampleFromSchema(propertySchema)
+			}
+		}
+		return example
+	case "array":
+		example := []interface{}{}
+		items, _ := schema["items"].(map[string]interface{})
+		count := 1
+		if minItems, ok := schema["minItems"].(float64); ok && int(minItems) > count {
+			count = int(minItems)
+		}
+		for i := 0; i < count && items != nil; i++ {
+			example = append(example, exampleFromSchema(items))
+		}
+		return example
+	case "integer", "number":
+		value := 0.0
+		if minimum, ok := schema["minimum"].(float64); ok {
+			value = minimum
+		}
+		if minimum, ok := schema["exclusiveMinimum"].(float64); ok {
+			value = minimum + 1
+		}
+		return value
+	case "boolean":
+		return true
+	case "null":
+		return nil
+	case "string":
+		return exampleString(schema)
+	}
+	return nil
+}
+
+func exampleString(schema map[string]interface{}) string {
+	var example string
+	switch schema["format"] {
+	case "email":
+		example = "user@example.com"
+	case "date-time":
+		example = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC).Format(time.RFC3339)
+	case "date":
+		example = "2024-01-01"
+	case "time":
+		example = "00:00:00Z"
+	case "uri", "url":
+		example = "https://example.com"
+	case "uuid":
+		example = "00000000-0000-4000-8000-000000000000"
+	case "ipv4":
+		example = "192.0.2.1"
+	case "ipv6":
+		example = "2001:db8::1"
+	case "hostname":
+		example = "example.com"
+	case "byte":
+		example = base64.StdEncoding.EncodeToString([]byte("example"))
+	default:
+		example = "string"
+	}
+
+	if minLength, ok := schema["minLength"].(float64); ok && len(example) < int(minLength) {
+		example += strings.Repeat("x", int(minLength)-len(example))
+	}
+	if maxLength, ok := schema["maxLength"].(float64); ok && len(example) > int(maxLength) {
+		example = example[:int(maxLength)]
+	}
+	return example
+}
+
+// addClientFlags adds the flags of contractClient to a command
+func addClientFlags(cmd *cobra.Command) {
+	cmd.Flags().String("cert", "", "Path to a PEM client certificate, for mtls endpoints")
+	cmd.Flags().String("key", "", "Path to the PEM private key of --cert")
+	cmd.Flags().String("cacert", "", "Path to PEM CA certificates to trust, eg. the CA written by serve --tls")
+	cmd.Flags().Duration("timeout", 30*time.Second, "Timeout of each request")
+}
+
+// contractClient returns the HTTP client configured by the flags of addClientFlags
+func contractClient(cmd *cobra.Command) (*http.Client, error) {
+	certFile, _ := cmd.Flags().GetString("cert")
+	keyFile, _ := cmd.Flags().GetString("key")
+	caFile, _ := cmd.Flags().GetString("cacert")
+	timeout, _ := cmd.Flags().GetDuration("timeout")
+
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if certFile != "" || keyFile != "" {
+		certificate, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("loading client certificate: %v", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{certificate}
+	}
+	if caFile != "" {
+		caPEM, err := utils.LoadFile(caFile)
+		if err != nil {
+			return nil, err
+		}
+		pool, err := server.LoadCertPool([]byte(caPEM))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", caFile, err)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	return &http.Client{Transport: transport, Timeout: timeout}, nil
+}
+
+// exchange sends the request of the contract, with the extra headers replacing its own, and
+// checks the response
+func (c *contract) exchange(client *http.Client, baseURL string, extra http.Header, exactHeaders bool) (*http.Request, *http.Response, []check, error) {
+	req, err := c.newRequest(baseURL)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	for name, values := range extra {
+		req.Header[name] = values
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return req, nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return req, resp, nil, err
+	}
+	return req, resp, c.checkResponse(resp, body, exactHeaders), nil
+}
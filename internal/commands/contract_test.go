@@ -0,0 +1,157 @@
+package commands
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/nicobistolfi/mockthis-cli/internal/server"
+	"github.com/stretchr/testify/assert"
+)
+
+const contractFile = `endpoint:
+  path: /users?active=true
+  auth:
+    type: bearer
+    token: secret
+  request:
+    content-type: application/json
+    schema: {"type": "object", "required": ["email"], "properties": {"email": {"type": "string", "format": "email"}}}
+  response:
+    method: POST
+    status: "201"
+    headers:
+      X-Trace: "on"
+    schema: {"type": "object", "required": ["id"]}
+    body: '{"id": "42"}'
+`
+
+func TestLoadContractOfNestedFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "payments", "charges.yml")
+	assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	assert.NoError(t, os.WriteFile(path, []byte("endpoint:\n  response:\n    body: charged\n"), 0644))
+
+	// Files without a path are called where serve --dir serves them
+	c, err := loadContract(path, dir, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "payments/charges", c.Name)
+	assert.Equal(t, "/payments/charges", c.Target)
+}
+
+func TestContractFromFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "users.yml")
+	assert.NoError(t, os.WriteFile(path, []byte(contractFile), 0644))
+
+	c, err := contractFromFile(dir, path, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "users", c.Name)
+	assert.Equal(t, http.MethodPost, c.Method)
+	assert.Equal(t, "/users?active=true", c.Target)
+	assert.Equal(t, 201, c.Status)
+	assert.Equal(t, map[string]string{"X-Trace": "on"}, c.Headers)
+	assert.NotNil(t, c.RequestSchema)
+	assert.NotNil(t, c.ResponseSchema)
+
+	endpoints, err := loadEndpoints(dir, nil)
+	assert.NoError(t, err)
+	srv, err := server.New(endpoints)
+	assert.NoError(t, err)
+
+	var received map[string]interface{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		_ = json.Unmarshal(body, &received)
+		srv.ServeHTTP(w, r)
+	}))
+	defer ts.Close()
+
+	_, resp, checks, err := c.exchange(ts.Client(), ts.URL, nil, true)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+	assert.Equal(t, map[string]interface{}{"email": "user@example.com"}, received)
+	assert.Len(t, checks, 4)
+	for _, c := range checks {
+		assert.NoError(t, c.Err, c.Name)
+	}
+}
+
+func TestCheckResponse(t *testing.T) {
+	c := &contract{
+		Status:         200,
+		ContentType:    "application/json",
+		Headers:        map[string]string{"X-Trace": "on"},
+		ResponseSchema: map[string]interface{}{"type": "object", "required": []interface{}{"id"}},
+	}
+
+	resp := &http.Response{StatusCode: 500, Header: http.Header{}}
+	resp.Header.Set("Content-Type", "text/plain")
+	resp.Header.Set("X-Trace", "off")
+
+	checks := c.checkResponse(resp, []byte(`{"name": "x"}`), true)
+	assert.Len(t, checks, 4)
+	for _, c := range checks {
+		assert.Error(t, c.Err, c.Name)
+	}
+
+	// Without exact headers, a present header is enough
+	checks = c.checkResponse(resp, nil, false)
+	assert.NoError(t, checks[2].Err)
+}
+
+func TestExampleFromSchema(t *testing.T) {
+	schema := map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"id":      map[string]interface{}{"type": "integer", "minimum": float64(1)},
+			"name":    map[string]interface{}{"type": "string", "minLength": float64(8)},
+			"role":    map[string]interface{}{"enum": []interface{}{"admin", "user"}},
+			"created": map[string]interface{}{"type": "string", "format": "date-time"},
+			"tags":    map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}},
+			"active":  map[string]interface{}{"type": "boolean", "default": false},
+			"nick":    map[string]interface{}{"type": "string", "example": "bob"},
+		},
+	}
+
+	assert.Equal(t, map[string]interface{}{
+		"id":      float64(1),
+		"name":    "stringxx",
+		"role":    "admin",
+		"created": "2024-01-01T00:00:00Z",
+		"tags":    []interface{}{"string"},
+		"active":  false,
+		"nick":    "bob",
+	}, exampleFromSchema(schema))
+}
+
+func TestAddCredentials(t *testing.T) {
+	tests := []struct {
+		name   string
+		auth   map[string]interface{}
+		header string
+		want   string
+		query  string
+	}{
+		{"bearer", map[string]interface{}{"type": "bearer", "token": "t"}, "Authorization", "Bearer t", ""},
+		{"oauth2 properties", map[string]interface{}{"type": "oauth2", "properties": map[string]interface{}{"accessToken": "a", "tokenType": "MAC"}}, "Authorization", "MAC a", ""},
+		{"basic", map[string]interface{}{"type": "basic", "username": "u", "password": "p"}, "Authorization", "Basic dTpw", ""},
+		{"apiKey header", map[string]interface{}{"type": "apiKey", "name": "X-Key", "value": "k", "in": "header"}, "X-Key", "k", ""},
+		{"apiKey query", map[string]interface{}{"type": "apiKey", "name": "key", "value": "k", "in": "query"}, "", "", "key=k"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			assert.NoError(t, addCredentials(req, tt.auth))
+			if tt.header != "" {
+				assert.Equal(t, tt.want, req.Header.Get(tt.header))
+			}
+			assert.Equal(t, tt.query, req.URL.RawQuery)
+		})
+	}
+}
@@ -3,6 +3,7 @@ package commands
 import (
 	"bytes"
 	_ "embed"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -10,8 +11,12 @@ import (
 	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/nicobistolfi/mockthis-cli/internal/config"
+	"github.com/nicobistolfi/mockthis-cli/internal/labels"
+	"github.com/nicobistolfi/mockthis-cli/internal/output"
+	"github.com/nicobistolfi/mockthis-cli/internal/server"
 	"github.com/nicobistolfi/mockthis-cli/internal/utils"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
@@ -21,7 +26,7 @@ import (
 
 // CreateEndpointCmd is the command to create a new mock endpoint
 var CreateEndpointCmd = &cobra.Command{
-	Use:   "create [--file <path>] [--auth-type <type>] [--auth-properties <properties>] [--request-content-type <type>] [--request-schema <schema>] [--method <method>] [--status <status>] [--content-type <type>] [--charset <charset>] [--headers <headers>] [--schema <schema>] [--body <body>]",
+	Use:   "create [--file <path>] [--label <labels>] [--ttl <duration> | --expires-at <time>] [--project <project>] [--path <path>] [--auth-type <type>] [--auth-properties <properties>] [--request-content-type <type>] [--request-schema <schema>] [--method <method>] [--status <status>] [--content-type <type>] [--charset <charset>] [--headers <headers>] [--schema <schema>] [--body <body> | --body-file <path> | --body-base64 <base64>] [--cors <cors>]",
 	Short: "Create a new mock endpoint",
 	Run:   createEndpoint,
 }
@@ -29,26 +34,60 @@ var CreateEndpointCmd = &cobra.Command{
 //go:embed schemas/endpoint.json
 var ENDPOINT_SCHEMA string
 
+// localOnlyKeys are endpoint file keys used by `mockthis serve` and ignored by `create`
+var localOnlyKeys = []string{"scenario"}
+
+// maxBodySize bounds the response body create sends, as sent: base64 encoded for binary
+// bodies. The limit of the API itself is reported when it answers 413.
+const maxBodySize = 5 << 20
+
 func init() {
+	addEndpointFlags(CreateEndpointCmd)
+	addCreatedOutputFlag(CreateEndpointCmd)
+}
+
+// addEndpointFlags adds the flags setting the fields of an endpoint, and reading them from a
+// file, to a command
+func addEndpointFlags(cmd *cobra.Command) {
 	// File
-	CreateEndpointCmd.Flags().StringP("file", "f", "", "Path to JSON or YAML file containing endpoint data")
+	cmd.Flags().StringP("file", "f", "", "Path to JSON or YAML file containing endpoint data")
+	addVarFlag(cmd)
+
+	addEndpointFieldFlags(cmd)
+}
+
+// addEndpointFieldFlags adds the flags setting the fields of an endpoint to a command
+func addEndpointFieldFlags(cmd *cobra.Command) {
+	// Metadata
+	cmd.Flags().StringSliceP("label", "l", nil, "Labels, comma-separated key=value pairs. Eg. 'team=payments,env=ci'")
+	cmd.Flags().String("ttl", "", "Time the endpoint lives for before it expires, in s, m, h, d or w. Eg. '2h' or '7d'")
+	cmd.Flags().String("expires-at", "", "Time the endpoint expires at, in RFC 3339. Eg. '2026-01-31T18:00:00Z'")
+
+	// Route
+	addProjectFlag(cmd)
+	cmd.Flags().String("path", "", "Path to serve the endpoint on under the mock base URL, with {name} parameters and * wildcards. Eg. '/users/{id}'")
 
 	// Response
-	CreateEndpointCmd.Flags().StringP("method", "m", "GET", "HTTP method (GET, POST, PUT, DELETE, etc.)")
-	CreateEndpointCmd.Flags().StringP("status", "s", "200", "HTTP status code")
-	CreateEndpointCmd.Flags().StringP("content-type", "c", "application/json", "Response Content-Type")
-	CreateEndpointCmd.Flags().String("charset", "", "Charset")
-	CreateEndpointCmd.Flags().StringP("headers", "H", "", "Response headers, comma-separated key=value pairs or JSON. Eg. 'H1: v1, H2: v2'")
-	CreateEndpointCmd.Flags().String("schema", "", "JSON Schema to validate the response body")
-	CreateEndpointCmd.Flags().StringP("body", "b", "Hello, World! 🌎", "Response body")
+	cmd.Flags().StringP("method", "m", "GET", "HTTP method (GET, POST, PUT, DELETE, etc.)")
+	cmd.Flags().StringP("status", "s", "200", "HTTP status code")
+	cmd.Flags().StringP("content-type", "c", "application/json", "Response Content-Type")
+	cmd.Flags().String("charset", "", "Charset")
+	cmd.Flags().StringP("headers", "H", "", "Response headers, comma-separated key=value pairs or JSON. Eg. 'H1: v1, H2: v2'")
+	cmd.Flags().String("schema", "", "JSON Schema to validate the response body")
+	cmd.Flags().StringP("body", "b", "Hello, World! 🌎", "Response body")
+	cmd.Flags().String("body-file", "", "Path to a file to use as the response body, for binary or large bodies")
+	cmd.Flags().String("body-base64", "", "Base64 encoded response body")
 
 	// Authentication
-	CreateEndpointCmd.Flags().String("auth-type", "", "Authentication type (basic, apiKey, bearer, oauth2, jwt)")
-	CreateEndpointCmd.Flags().String("auth-properties", "", "Authentication properties (comma-separated key=value pairs)")
+	cmd.Flags().String("auth-type", "", "Authentication type (basic, apiKey, bearer, oauth2, jwt, mtls)")
+	cmd.Flags().String("auth-properties", "", "Authentication properties (comma-separated key=value pairs)")
 
 	// Request
-	CreateEndpointCmd.Flags().String("request-content-type", "application/json", "Request Content-Type")
-	CreateEndpointCmd.Flags().String("request-schema", "", "JSON Schema to validate the request body")
+	cmd.Flags().String("request-content-type", "application/json", "Request Content-Type")
+	cmd.Flags().String("request-schema", "", "JSON Schema to validate the request body")
+
+	// CORS
+	cmd.Flags().String("cors", "", "CORS policy, comma-separated allowed origins or JSON. Eg. '{\"origins\": [\"*\"], \"credentials\": true}'")
 }
 
 func createEndpoint(cmd *cobra.Command, args []string) {
@@ -57,19 +96,32 @@ func createEndpoint(cmd *cobra.Command, args []string) {
 		fmt.Println("Error parsing command arguments:", err)
 		os.Exit(1)
 	}
+	projectID, err := projectFlag(cmd, getConfig())
+	if err != nil {
+		fmt.Println("Error finding project:", err)
+		os.Exit(1)
+	}
+	if projectID != "" {
+		endpointData["projectId"] = projectID
+	}
 	response := queryAPIEndpoint(endpointData)
-	message, err := processAPIResponse(response)
+	created, err := decodeCreateResponse(response)
 	if err != nil {
 		fmt.Println("Error processing API response:", err)
 		os.Exit(1)
 	}
-	fmt.Println(message)
+	printCreated(cmd, created)
+
+	// Endpoints created from files can then be referred to by file or name
+	if filePath, _ := cmd.Flags().GetString("file"); filePath != "" {
+		if err := recordEndpoint(filePath, created.ID, created.MockURL); err != nil {
+			fmt.Println("Error recording the endpoint in the state:", err)
+			os.Exit(1)
+		}
+	}
 }
 
 func parseCommandArguments(cmd *cobra.Command) (map[string]interface{}, error) {
-
-	var endpointData map[string]interface{}
-
 	filePath, _ := cmd.Flags().GetString("file")
 	if filePath != "" {
 		err := loadFromFile(filePath, cmd)
@@ -77,7 +129,13 @@ func parseCommandArguments(cmd *cobra.Command) (map[string]interface{}, error) {
 			return nil, err
 		}
 	}
-	endpointData = loadFromFlags(cmd)
+	return endpointPayload(cmd)
+}
+
+// endpointPayload builds the create payload of the endpoint set by the flags of
+// addEndpointFieldFlags
+func endpointPayload(cmd *cobra.Command) (map[string]interface{}, error) {
+	endpointData := loadFromFlags(cmd)
 
 	// Convert status to int or set to 200 if not present or conversion fails
 	if status, ok := endpointData["status"].(string); ok {
@@ -90,14 +148,39 @@ func parseCommandArguments(cmd *cobra.Command) (map[string]interface{}, error) {
 		endpointData["status"] = 200
 	}
 
+	labelFlags, _ := cmd.Flags().GetStringSlice("label")
+	if len(labelFlags) > 0 {
+		endpointLabels, remove, err := labels.Parse(labelFlags)
+		if err != nil {
+			return nil, err
+		}
+		if len(remove) > 0 {
+			return nil, fmt.Errorf("labels can only be removed on update")
+		}
+		endpointData["labels"] = endpointLabels
+	}
+
+	if err := processExpiry(cmd, endpointData); err != nil {
+		return nil, err
+	}
+
+	if path, ok := endpointData["path"].(string); ok {
+		if err := server.ValidatePath(path); err != nil {
+			return nil, err
+		}
+	}
+
 	authType, _ := cmd.Flags().GetString("auth-type")
 	authProperties, _ := cmd.Flags().GetString("auth-properties")
 
 	if authType != "" && authProperties != "" {
-		endpointData["authCredentials"] = processAuthCredentials(authType, authProperties)
+		authCredentials, err := processAuthCredentials(authType, authProperties)
+		if err != nil {
+			return nil, err
+		}
+		endpointData["authCredentials"] = authCredentials
 	} else if (authType != "" && authProperties == "") || (authType == "" && authProperties != "") {
-		fmt.Println("auth-type and auth-properties are required when using authentication.")
-		os.Exit(1)
+		return nil, fmt.Errorf("auth-type and auth-properties are required when using authentication")
 	}
 
 	// Ensure correct types for specific fields
@@ -119,12 +202,24 @@ func parseCommandArguments(cmd *cobra.Command) (map[string]interface{}, error) {
 		endpointData["charset"] = "UTF-8"
 	}
 
+	if err := processResponseBody(endpointData); err != nil {
+		return nil, err
+	}
+
 	if responseBody, ok := endpointData["responseBody"].(string); ok {
 		endpointData["responseBody"] = responseBody
 	} else {
 		endpointData["responseBody"] = nil
 	}
 
+	if cors, ok := endpointData["cors"].(string); ok {
+		corsPolicy, err := processCORS(cors)
+		if err != nil {
+			return nil, err
+		}
+		endpointData["cors"] = corsPolicy
+	}
+
 	for _, field := range []string{"responseBodySchema", "requestContentType", "requestBodySchema"} {
 		if value, ok := endpointData[field].(string); ok {
 			endpointData[field] = value
@@ -137,6 +232,35 @@ func parseCommandArguments(cmd *cobra.Command) (map[string]interface{}, error) {
 	return endpointData, nil
 }
 
+// endpointPayloadFromFile builds the create payload of an endpoint file, as `create --file`
+// does. vars are the --var variables.
+func endpointPayloadFromFile(filePath string, vars map[string]string) (map[string]interface{}, error) {
+	cmd, err := endpointFileCommand(filePath, vars)
+	if err != nil {
+		return nil, err
+	}
+	return endpointPayload(cmd)
+}
+
+// endpointFileCommand returns a command with the flags of `create --file` set from an
+// endpoint file. vars are the --var variables.
+func endpointFileCommand(filePath string, vars map[string]string) (*cobra.Command, error) {
+	cmd := &cobra.Command{}
+	addEndpointFlags(cmd)
+	if err := cmd.Flags().Set("file", filePath); err != nil {
+		return nil, err
+	}
+	for name, value := range vars {
+		if err := cmd.Flags().Set("var", name+"="+value); err != nil {
+			return nil, err
+		}
+	}
+	if err := loadFromFile(filePath, cmd); err != nil {
+		return nil, err
+	}
+	return cmd, nil
+}
+
 func getConfig() *config.Data {
 	configData, err := config.LoadConfig(config.TokenFile)
 	if err != nil {
@@ -169,26 +293,67 @@ func queryAPIEndpoint(endpointData map[string]interface{}) *http.Response {
 	return resp
 }
 
-func processAPIResponse(resp *http.Response) (string, error) {
-	defer resp.Body.Close()
+// createResponse is the response of the API to the creation of an endpoint
+type createResponse struct {
+	MockURL  string                 `json:"mockUrl"`
+	ID       string                 `json:"id"`
+	Endpoint map[string]interface{} `json:"endpoint"`
+}
 
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("failed to create endpoint. Status: %s", resp.Status)
+func (r *createResponse) message() string {
+	table := buildTableFromMap(r.Endpoint)
+	return fmt.Sprintf("Endpoint created successfully!\nMock URL: %s\n\n%s", r.MockURL, table)
+}
+
+// addCreatedOutputFlag adds the flag choosing how the created endpoint is printed
+func addCreatedOutputFlag(cmd *cobra.Command) {
+	cmd.Flags().StringP("output", "o", "", "Output format of the created endpoint, a summary by default: "+output.Formats)
+}
+
+// printCreated prints the created endpoint in the format of the flag of addCreatedOutputFlag
+func printCreated(cmd *cobra.Command, created *createResponse) {
+	format, _ := cmd.Flags().GetString("output")
+	if format == "" {
+		fmt.Println(created.message())
+		return
 	}
 
-	var createResponse struct {
-		MockURL  string                 `json:"mockUrl"`
-		ID       string                 `json:"id"`
-		Endpoint map[string]interface{} `json:"endpoint"`
+	endpoint := created.Endpoint
+	if endpoint == nil {
+		endpoint = map[string]interface{}{"id": created.ID}
+	}
+	if _, ok := endpoint["endpointUrl"]; !ok {
+		endpoint["endpointUrl"] = created.MockURL
+	}
+	if err := output.PrintOne(os.Stdout, format, endpoint, endpointColumns); err != nil {
+		fmt.Println("Error printing endpoint:", err)
+		os.Exit(1)
 	}
-	err := json.NewDecoder(resp.Body).Decode(&createResponse)
+}
+
+func processAPIResponse(resp *http.Response) (string, error) {
+	created, err := decodeCreateResponse(resp)
 	if err != nil {
-		return "", fmt.Errorf("error decoding API response: %v", err)
+		return "", err
 	}
+	return created.message(), nil
+}
 
-	table := buildTableFromMap(createResponse.Endpoint)
+func decodeCreateResponse(resp *http.Response) (*createResponse, error) {
+	defer resp.Body.Close()
 
-	return fmt.Sprintf("Endpoint created successfully!\nMock URL: %s\n\n%s", createResponse.MockURL, table), nil
+	if resp.StatusCode == http.StatusRequestEntityTooLarge {
+		return nil, fmt.Errorf("failed to create endpoint, the response body is too large. Status: %s", resp.Status)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to create endpoint. Status: %s", resp.Status)
+	}
+
+	var created createResponse
+	if err := json.NewDecoder(resp.Body).Decode(&created); err != nil {
+		return nil, fmt.Errorf("error decoding API response: %v", err)
+	}
+	return &created, nil
 }
 
 // Print a table from a map and keep it aligned to the left
@@ -253,7 +418,7 @@ func formatValue(value interface{}) string {
 }
 
 // ProcessAuthCredentials processes the authentication credentials from a string that can be either a JSON or a comma-separated list of key=value pairs
-func processAuthCredentials(authType, authProperties string) map[string]interface{} {
+func processAuthCredentials(authType, authProperties string) (map[string]interface{}, error) {
 	authCredentials := map[string]interface{}{
 		"type": authType,
 	}
@@ -262,8 +427,7 @@ func processAuthCredentials(authType, authProperties string) map[string]interfac
 	if utils.IsJSON(authProperties) {
 		err := json.Unmarshal([]byte(authProperties), &authPropertiesMap)
 		if err != nil {
-			fmt.Println("Error unmarshalling JSON:", err)
-			os.Exit(1)
+			return nil, fmt.Errorf("error unmarshalling auth properties: %w", err)
 		}
 	} else {
 		propertyPairs := strings.Split(authProperties, ",")
@@ -293,21 +457,215 @@ func processAuthCredentials(authType, authProperties string) map[string]interfac
 		authCredentials["refreshToken"] = authPropertiesMap["refreshToken"]
 	case "jwt":
 		authCredentials["token"] = authPropertiesMap["token"]
+	case "mtls":
+		authCredentials["subject"] = authPropertiesMap["subject"]
+		authCredentials["san"] = authPropertiesMap["san"]
 	default:
-		fmt.Println("Invalid authentication type. Supported types: basic, apikey, bearer, oauth2, jwt. Got:", authType)
-		os.Exit(1)
+		return nil, fmt.Errorf("invalid authentication type %s, supported types: basic, apiKey, bearer, oauth2, jwt, mtls", authType)
+	}
+
+	return authCredentials, nil
+}
+
+// processResponseBody base64 encodes bodies given with --body-file or --body-base64 and
+// checks the body fits in the size accepted by the API
+func processResponseBody(endpointData map[string]interface{}) error {
+	bodyFile, hasFile := endpointData["responseBodyFile"].(string)
+	encoded, hasBase64 := endpointData["responseBodyBase64"].(string)
+	_, hasBody := endpointData["responseBody"].(string)
+	delete(endpointData, "responseBodyFile")
+	delete(endpointData, "responseBodyBase64")
+
+	sources := 0
+	for _, has := range []bool{hasFile, hasBase64, hasBody} {
+		if has {
+			sources++
+		}
+	}
+	if sources > 1 {
+		return fmt.Errorf("only one of --body, --body-file and --body-base64 can be used")
+	}
+
+	// The size checked is the size of the body as sent
+	var size int
+	switch {
+	case hasFile:
+		content, err := os.ReadFile(bodyFile)
+		if err != nil {
+			return fmt.Errorf("error reading body file: %v", err)
+		}
+		encoded = base64.StdEncoding.EncodeToString(content)
+		size = len(encoded)
+	case hasBase64:
+		content, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(encoded), ""))
+		if err != nil {
+			return fmt.Errorf("invalid base64 body: %v", err)
+		}
+		encoded = base64.StdEncoding.EncodeToString(content)
+		size = len(encoded)
+	case hasBody:
+		size = len(endpointData["responseBody"].(string))
+	}
+
+	if size > maxBodySize {
+		return fmt.Errorf("the response body is %d bytes as sent, the maximum is %d bytes", size, maxBodySize)
+	}
+
+	if hasFile || hasBase64 {
+		endpointData["responseBody"] = encoded
+		endpointData["responseBodyEncoding"] = "base64"
 	}
+	return nil
+}
+
+// processExpiry sets expiresAt from --ttl, relative to now, and checks the expiry is a
+// time in the future
+func processExpiry(cmd *cobra.Command, endpointData map[string]interface{}) error {
+	ttl, _ := cmd.Flags().GetString("ttl")
+	if ttl != "" {
+		if cmd.Flags().Changed("expires-at") {
+			return fmt.Errorf("only one of --ttl and --expires-at can be used")
+		}
+		d, err := utils.ParseDuration(ttl)
+		if err != nil {
+			return err
+		}
+		if d <= 0 {
+			return fmt.Errorf("--ttl must be positive")
+		}
+		endpointData["expiresAt"] = time.Now().Add(d).UTC().Format(time.RFC3339)
+		return nil
+	}
+
+	expiresAt, ok := endpointData["expiresAt"].(string)
+	if !ok {
+		return nil
+	}
+	t, err := time.Parse(time.RFC3339, expiresAt)
+	if err != nil {
+		return fmt.Errorf("invalid expiry %q, use RFC 3339. Eg. 2026-01-31T18:00:00Z", expiresAt)
+	}
+	if !t.After(time.Now()) {
+		return fmt.Errorf("the expiry %s is in the past", expiresAt)
+	}
+	endpointData["expiresAt"] = t.UTC().Format(time.RFC3339)
+	return nil
+}
 
-	return authCredentials
+// pastExpiry reports whether expiresAt is an RFC 3339 time that has passed
+func pastExpiry(expiresAt string) bool {
+	t, err := time.Parse(time.RFC3339, expiresAt)
+	return err == nil && !t.After(time.Now())
+}
+
+// dropPastExpiry unsets the --expires-at of cmd when it has passed, and returns it. Files
+// written from endpoints keep their expiry, which can't be created again once passed.
+func dropPastExpiry(cmd *cobra.Command) string {
+	expiresAt, _ := cmd.Flags().GetString("expires-at")
+	if !pastExpiry(expiresAt) {
+		return ""
+	}
+	resetFlag(cmd.Flags(), "expires-at")
+	return expiresAt
+}
+
+// processCORS builds the CORS policy of the create payload from either a JSON policy in the
+// endpoint file format or a comma-separated list of allowed origins
+func processCORS(cors string) (map[string]interface{}, error) {
+	if !utils.IsJSON(cors) {
+		origins := []interface{}{}
+		for _, origin := range strings.Split(cors, ",") {
+			if origin = strings.TrimSpace(origin); origin != "" {
+				origins = append(origins, origin)
+			}
+		}
+		return map[string]interface{}{"origins": origins}, nil
+	}
+
+	corsMap, err := utils.ParseJSON(cors)
+	if err != nil {
+		return nil, err
+	}
+	policy := make(map[string]interface{}, len(corsMap))
+	for key, value := range corsMap {
+		policy[toCamelCase(key)] = value
+	}
+	return policy, nil
 }
 
 func loadFromFile(filePath string, cmd *cobra.Command) error {
-	data, err := utils.LoadFile(filePath)
+	vars, err := varFlags(cmd)
+	if err != nil {
+		return err
+	}
+	endpoint, err := readEndpointFile(filePath, vars)
 	if err != nil {
-		fmt.Printf("Error reading file: %v\n", err)
 		return err
 	}
 
+	// The CORS policy is passed as a single JSON flag rather than one flag per field
+	if cors, ok := endpoint["cors"]; ok {
+		endpoint["cors"], err = utils.ToJSON(cors)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Labels of the file are set before those of --label, which take precedence
+	if fileLabels := labels.FromValue(endpoint["labels"]); len(fileLabels) > 0 {
+		pairs := strings.Split(labels.Format(fileLabels), ",")
+		flagLabels, _ := cmd.Flags().GetStringSlice("label")
+		if flag := cmd.Flags().Lookup("label"); flag != nil {
+			if err := flag.Value.(pflag.SliceValue).Replace(append(pairs, flagLabels...)); err != nil {
+				return err
+			}
+			flag.Changed = true
+		}
+	}
+	delete(endpoint, "labels")
+
+	// An expiry given with --ttl or --expires-at takes precedence over the one of the file
+	if expiresAt, ok := endpoint["expiresAt"]; ok {
+		delete(endpoint, "expiresAt")
+		ttl, _ := cmd.Flags().GetString("ttl")
+		if !cmd.Flags().Changed("expires-at") && ttl == "" {
+			if err := cmd.Flags().Set("expires-at", fmt.Sprintf("%v", expiresAt)); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Keys only the local server understands are not part of the create payload
+	for _, key := range localOnlyKeys {
+		delete(endpoint, key)
+	}
+
+	// body_file was resolved to body_base64 when reading the file
+	if response, ok := endpoint["response"].(map[string]interface{}); ok {
+		if encoded, ok := response["body_base64"]; ok {
+			delete(response, "body_base64")
+			response["body-base64"] = encoded
+		}
+	}
+
+	utils.MapToFlags(endpoint, cmd)
+	return nil
+}
+
+// readEndpointFile parses, expands and validates an endpoint file and returns its "endpoint"
+// block. vars are the --var variables.
+func readEndpointFile(filePath string, vars map[string]string) (map[string]interface{}, error) {
+	content, err := utils.LoadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	// Includes and variables are resolved before the file is validated
+	data, err := expandEndpointFile(filePath, content, vars)
+	if err != nil {
+		return nil, err
+	}
+
 	var endpointData map[string]interface{}
 	ext := filepath.Ext(filePath)
 
@@ -317,30 +675,78 @@ func loadFromFile(filePath string, cmd *cobra.Command) error {
 	case utils.IsYAML(data):
 		endpointData, err = utils.ParseYAML(data)
 	default:
-		fmt.Printf("Unsupported file format: %s. Use JSON or YAML.\n", ext)
-		err = fmt.Errorf("unsupported file format: %s", ext)
-		return err
+		return nil, fmt.Errorf("unsupported file format: %s, use JSON or YAML", ext)
 	}
 
 	if err != nil {
-		fmt.Printf("Error parsing file: %v\n", err)
-		return err
+		return nil, fmt.Errorf("error parsing %s: %w", filePath, err)
 	}
 
 	// Validate the parsed data against the schema
 	err = utils.ValidateAgainstSchema(endpointData, ENDPOINT_SCHEMA)
 	if err != nil {
-		fmt.Printf("Error validating endpoint data: %v\n", err)
-		return err
+		// Report where in the file each error is
+		if doc, _ := parseEndpointDocument(filePath, content, vars); doc != nil {
+			if diagnostics := doc.schemaDiagnostics(); len(diagnostics) > 0 {
+				err = diagnosticsError(diagnostics)
+			}
+		}
+		return nil, err
+	}
+
+	// Read every version of the file format into the same shape
+	if err := normalizeEndpointFile(endpointData); err != nil {
+		return nil, err
 	}
 
 	// Check if "endpoint" key exists and is a map
 	endpoint, ok := endpointData["endpoint"].(map[string]interface{})
 	if !ok {
-		return fmt.Errorf("endpoint data is not a map or the endpoint key is not found")
+		return nil, fmt.Errorf("endpoint data is not a map or the endpoint key is not found")
+	}
+
+	if err := resolveBodyFiles(endpoint, filepath.Dir(filePath)); err != nil {
+		return nil, fmt.Errorf("error reading body file: %w", err)
+	}
+
+	return endpoint, nil
+}
+
+// resolveBodyFiles replaces the body_file of every response of the endpoint with the
+// base64 encoded content of the file, read relative to baseDir
+func resolveBodyFiles(endpoint map[string]interface{}, baseDir string) error {
+	var responses []map[string]interface{}
+	if response, ok := endpoint["response"].(map[string]interface{}); ok {
+		responses = append(responses, response)
+	}
+	if scenario, ok := endpoint["scenario"].(map[string]interface{}); ok {
+		entries, _ := scenario["responses"].([]interface{})
+		for _, entry := range entries {
+			if entryMap, ok := entry.(map[string]interface{}); ok {
+				if response, ok := entryMap["response"].(map[string]interface{}); ok {
+					responses = append(responses, response)
+				}
+			}
+		}
+	}
+
+	for _, response := range responses {
+		bodyFile, ok := response["body_file"].(string)
+		if !ok {
+			continue
+		}
+		if !filepath.IsAbs(bodyFile) {
+			bodyFile = filepath.Join(baseDir, bodyFile)
+		}
+
+		content, err := os.ReadFile(bodyFile)
+		if err != nil {
+			return err
+		}
+		delete(response, "body_file")
+		response["body_base64"] = base64.StdEncoding.EncodeToString(content)
 	}
 
-	utils.MapToFlags(endpoint, cmd)
 	return nil
 }
 
@@ -355,9 +761,17 @@ func loadFromFlags(cmd *cobra.Command) map[string]interface{} {
 		"schema":               "responseBodySchema",
 		"request-schema":       "requestBodySchema",
 		"headers":              "httpHeaders",
+		"body-file":            "responseBodyFile",
+		"body-base64":          "responseBodyBase64",
 	}
 
 	cmd.Flags().VisitAll(func(flag *pflag.Flag) {
+		// Flags saying how to read the endpoint file, the project resolved to its ID, the
+		// labels parsed into an object, the TTL turned into expiresAt and the output format
+		// are not fields of the endpoint
+		if flag.Name == "file" || flag.Name == "var" || flag.Name == "project" || flag.Name == "label" || flag.Name == "ttl" || flag.Name == "output" {
+			return
+		}
 		if flag.Changed {
 			key := flag.Name
 			if mappedKey, exists := flagMappings[key]; exists {
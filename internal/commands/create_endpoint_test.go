@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"io"
 	"net/http"
+	"os"
 	"path"
 	"reflect"
 	"runtime"
@@ -51,11 +52,24 @@ func TestProcessAuthCredentials(t *testing.T) {
 				"token": "abcdef123456",
 			},
 		},
+		{
+			name:           "Mutual TLS",
+			authType:       "mtls",
+			authProperties: "subject=billing,san=billing.internal",
+			expected: map[string]interface{}{
+				"type":    "mtls",
+				"subject": "billing",
+				"san":     "billing.internal",
+			},
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result := processAuthCredentials(tt.authType, tt.authProperties)
+			result, err := processAuthCredentials(tt.authType, tt.authProperties)
+			if err != nil {
+				t.Fatalf("processAuthCredentials() error = %v", err)
+			}
 			if !reflect.DeepEqual(result, tt.expected) {
 				t.Errorf("processAuthCredentials() = %v, want %v", result, tt.expected)
 			}
@@ -63,6 +77,63 @@ func TestProcessAuthCredentials(t *testing.T) {
 	}
 }
 
+func TestProcessAuthCredentialsErrors(t *testing.T) {
+	tests := []struct {
+		name           string
+		authType       string
+		authProperties string
+		wantErr        string
+	}{
+		{"Unknown type", "digest", "username=user", "invalid authentication type digest, supported types: basic, apiKey, bearer, oauth2, jwt, mtls"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := processAuthCredentials(tt.authType, tt.authProperties)
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("processAuthCredentials() error = %v, want %s", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestProcessCORS(t *testing.T) {
+	tests := []struct {
+		name     string
+		cors     string
+		expected map[string]interface{}
+	}{
+		{
+			name: "Origins",
+			cors: "https://a.example.com, https://b.example.com",
+			expected: map[string]interface{}{
+				"origins": []interface{}{"https://a.example.com", "https://b.example.com"},
+			},
+		},
+		{
+			name: "JSON policy",
+			cors: `{"origins": ["*"], "credentials": true, "max-age": 600}`,
+			expected: map[string]interface{}{
+				"origins":     []interface{}{"*"},
+				"credentials": true,
+				"maxAge":      float64(600),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := processCORS(tt.cors)
+			if err != nil {
+				t.Fatalf("processCORS() error = %v", err)
+			}
+			if !reflect.DeepEqual(result, tt.expected) {
+				t.Errorf("processCORS() = %v, want %v", result, tt.expected)
+			}
+		})
+	}
+}
+
 // nolint
 func TestLoadFromFlags(t *testing.T) {
 	cmd := &cobra.Command{}
@@ -71,12 +142,14 @@ func TestLoadFromFlags(t *testing.T) {
 	cmd.Flags().String("content-type", "", "")
 	cmd.Flags().String("charset", "", "")
 	cmd.Flags().String("body", "", "")
+	cmd.Flags().String("output", "", "")
 
 	cmd.Flags().Set("method", "GET")
 	cmd.Flags().Set("status", "200")
 	cmd.Flags().Set("content-type", "application/json")
 	cmd.Flags().Set("charset", "UTF-8")
 	cmd.Flags().Set("body", "Hello, World!")
+	cmd.Flags().Set("output", "json")
 
 	expected := map[string]interface{}{
 		"method":              "GET",
@@ -208,6 +281,73 @@ func TestProcessAPIResponse(t *testing.T) {
 	}
 }
 
+func TestProcessResponseBody(t *testing.T) {
+	bodyFile := path.Join(t.TempDir(), "body.bin")
+	if err := os.WriteFile(bodyFile, []byte{0x00, 0xff, 0x10}, 0644); err != nil {
+		t.Fatal(err)
+	}
+	// Under the limit decoded, over it base64 encoded as sent
+	largeFile := path.Join(t.TempDir(), "large.bin")
+	if err := os.WriteFile(largeFile, make([]byte, maxBodySize*3/4+3), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name         string
+		endpointData map[string]interface{}
+		expected     map[string]interface{}
+		wantErr      bool
+	}{
+		{
+			name:         "Body file",
+			endpointData: map[string]interface{}{"responseBodyFile": bodyFile},
+			expected:     map[string]interface{}{"responseBody": "AP8Q", "responseBodyEncoding": "base64"},
+		},
+		{
+			name:         "Base64 body",
+			endpointData: map[string]interface{}{"responseBodyBase64": "AP8Q"},
+			expected:     map[string]interface{}{"responseBody": "AP8Q", "responseBodyEncoding": "base64"},
+		},
+		{
+			name:         "Text body",
+			endpointData: map[string]interface{}{"responseBody": "Hello"},
+			expected:     map[string]interface{}{"responseBody": "Hello"},
+		},
+		{
+			name:         "Body and body file",
+			endpointData: map[string]interface{}{"responseBody": "Hello", "responseBodyFile": bodyFile},
+			wantErr:      true,
+		},
+		{
+			name:         "Invalid base64",
+			endpointData: map[string]interface{}{"responseBodyBase64": "not base64!"},
+			wantErr:      true,
+		},
+		{
+			name:         "Body too large",
+			endpointData: map[string]interface{}{"responseBody": strings.Repeat("a", maxBodySize+1)},
+			wantErr:      true,
+		},
+		{
+			name:         "Encoded body too large",
+			endpointData: map[string]interface{}{"responseBodyFile": largeFile},
+			wantErr:      true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := processResponseBody(tt.endpointData)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("processResponseBody() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(tt.endpointData, tt.expected) {
+				t.Errorf("processResponseBody() = %v, want %v", tt.endpointData, tt.expected)
+			}
+		})
+	}
+}
+
 func TestLoadFromFile(t *testing.T) {
 	// Get the directory of the current file
 	_, filename, _, _ := runtime.Caller(0)
@@ -242,6 +382,15 @@ func TestLoadFromFile(t *testing.T) {
 				"body":         "Hello, World!",
 			},
 		},
+		{
+			name:     "YAML file with a body file",
+			filePath: path.Join(testDataDir, "body-file.yaml"),
+			wantErr:  false,
+			expected: map[string]string{
+				"content-type": "image/png",
+				"body-base64":  "iVBORw0KGgoAAA==",
+			},
+		},
 		{
 			name:     "Invalid file format",
 			filePath: path.Join(testDataDir, "invalid.txt"),
@@ -284,3 +433,24 @@ func TestLoadFromFile(t *testing.T) {
 		})
 	}
 }
+
+func TestSetBodyEncoding(t *testing.T) {
+	tests := []struct {
+		name     string
+		endpoint map[string]interface{}
+		expected interface{}
+	}{
+		{"Base64 body", map[string]interface{}{"responseBody": "AP8Q", "responseBodyEncoding": "base64"}, "base64"},
+		{"Text body", map[string]interface{}{"responseBody": "Hello"}, "text"},
+		{"No body", map[string]interface{}{"responseBody": nil}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setBodyEncoding(tt.endpoint)
+			if got := tt.endpoint["responseBodyEncoding"]; got != tt.expected {
+				t.Errorf("setBodyEncoding() set %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
@@ -2,18 +2,36 @@ package commands
 
 import (
 	"fmt"
-	"net/http"
+	"os"
 
 	"github.com/nicobistolfi/mockthis-cli/internal/config"
 	"github.com/spf13/cobra"
 )
 
-// DeleteEndpointCmd is the command to delete an existing mock endpoint
+// DeleteEndpointCmd is the command to delete existing mock endpoints
 var DeleteEndpointCmd = &cobra.Command{
-	Use:   "delete [id]",
-	Short: "Delete an existing mock endpoint",
-	Args:  cobra.ExactArgs(1),
-	Run:   deleteEndpoint,
+	Use:   "delete [id, name or file...] | - | --all | -l <selector>",
+	Short: "Delete existing mock endpoints",
+	Long: `Delete existing mock endpoints, given by ID, read from stdin with -, or selected with --all
+or a label selector with -l among the endpoints of the current project. Endpoints created
+from files can be given by the path of their file, or its name in the state of the working
+directory. --all needs a project, given with --project or set as current, and selects the
+endpoints of every project only with --all-projects.
+
+Deleting more than one endpoint asks for confirmation unless --yes is given. IDs read from
+stdin require --yes. Endpoints are deleted concurrently, and the failures are reported at
+the end.`,
+	Example: `  mockthis delete 1a2b3c 4d5e6f
+  mockthis delete -l env=ci --yes
+  mockthis list -o json | jq -r '.[].id' | mockthis delete - --yes`,
+	Args: cobra.ArbitraryArgs,
+	Run:  deleteEndpoint,
+}
+
+func init() {
+	addSelectionFlags(DeleteEndpointCmd, "delete", "l")
+	DeleteEndpointCmd.Flags().BoolP("yes", "y", false, "Delete without asking for confirmation")
+	addBulkFlags(DeleteEndpointCmd)
 }
 
 func deleteEndpoint(cmd *cobra.Command, args []string) {
@@ -22,25 +40,64 @@ func deleteEndpoint(cmd *cobra.Command, args []string) {
 		fmt.Println("You need to login first.")
 		return
 	}
-	token := configData.Token
-
-	id := args[0]
 
-	req, _ := http.NewRequest("DELETE", config.BaseURL+"/endpoints/"+id, nil)
-	req.Header.Set("Authorization", "Bearer "+token)
+	yes, _ := cmd.Flags().GetBool("yes")
+	options, err := bulkFlags(cmd)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	// The confirmation would be read from the same stdin as the IDs
+	for _, arg := range args {
+		if arg == "-" && !yes {
+			fmt.Println("deleting IDs read from stdin requires --yes")
+			os.Exit(1)
+		}
+	}
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	ids, err := bulkIDs(cmd, configData, args)
 	if err != nil {
-		fmt.Println("Error deleting endpoint:", err)
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	if len(ids) == 0 {
+		fmt.Println("No endpoints to delete")
 		return
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusNoContent {
-		fmt.Println("Failed to delete endpoint. Status:", resp.Status)
+	// Deleting one endpoint by ID is not worth a confirmation, as before bulk deletes
+	if len(args) == 0 || len(ids) > 1 {
+		if !yes && !confirm(fmt.Sprintf("Delete these %d endpoints?", len(ids))) {
+			fmt.Println("Aborted")
+			return
+		}
+	}
+
+	failures := deleteEndpoints(configData.Token, ids, options)
+	if err := forgetEndpoints(deletedIDs(ids, failures), args); err != nil {
+		fmt.Println("Error updating the state:", err)
+	}
+	if len(ids) == 1 && len(failures) == 0 {
+		fmt.Println("Endpoint deleted successfully!")
 		return
 	}
+	printBulkSummary(os.Stdout, "Deleted", len(ids), failures)
+	if len(failures) > 0 {
+		os.Exit(1)
+	}
+}
 
-	fmt.Println("Endpoint deleted successfully!")
+// deletedIDs returns the IDs a bulk delete didn't fail on
+func deletedIDs(ids []string, failures []bulkFailure) []string {
+	failed := make(map[string]bool, len(failures))
+	for _, failure := range failures {
+		failed[failure.ID] = true
+	}
+	var deleted []string
+	for _, id := range ids {
+		if !failed[id] {
+			deleted = append(deleted, id)
+		}
+	}
+	return deleted
 }
@@ -0,0 +1,397 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/nicobistolfi/mockthis-cli/internal/config"
+	"github.com/nicobistolfi/mockthis-cli/internal/utils"
+	"github.com/pmezard/go-difflib/difflib"
+	"github.com/spf13/cobra"
+)
+
+// DiffCmd is the command to show the drift between endpoint files and deployed endpoints
+var DiffCmd = &cobra.Command{
+	Use:   "diff [--dir <dir>]",
+	Short: "Show the differences between endpoint files and deployed endpoints",
+	Long: `Compare the endpoint files of a directory with the endpoints of the project, field by field.
+
+A file is compared with the endpoint created or pulled from it, recorded in the state of the
+directory, or else with the endpoint of the same method and path. Files without a deployed
+endpoint and endpoints without a file are reported too. Files that changed since they were
+created or pulled are marked as edited locally.
+
+The exit status is 0 without drift, 1 with drift and 2 when the comparison failed.`,
+	Example: `  mockthis diff --dir mocks/
+  mockthis diff --dir mocks/ -o json`,
+	Args: cobra.NoArgs,
+	Run:  diffEndpoints,
+}
+
+// Drift states of an endpoint
+const (
+	driftUnchanged   = "unchanged"
+	driftChanged     = "changed"
+	driftNotDeployed = "not-deployed"
+	driftNoFile      = "no-file"
+	driftError       = "error"
+)
+
+// endpointDrift is the comparison of an endpoint file with its deployed endpoint
+type endpointDrift struct {
+	File    string        `json:"file,omitempty"`
+	ID      string        `json:"id,omitempty"`
+	MockURL string        `json:"mockUrl,omitempty"`
+	Status  string        `json:"status"`
+	Changes []fieldChange `json:"changes,omitempty"`
+	Error   string        `json:"error,omitempty"`
+	// LocallyEdited is set when the file changed since the endpoint was created or pulled
+	// from it, telling local edits from edits of the deployed endpoint
+	LocallyEdited bool `json:"locallyEdited,omitempty"`
+
+	local, remote map[string]interface{}
+}
+
+// fieldChange is a field with a different value in the file and in the deployed endpoint.
+// Fields missing on one side have no value there.
+type fieldChange struct {
+	Field  string      `json:"field"`
+	Local  interface{} `json:"local,omitempty"`
+	Remote interface{} `json:"remote,omitempty"`
+}
+
+func init() {
+	DiffCmd.Flags().String("dir", ".", "Directory of the endpoint files")
+	DiffCmd.Flags().StringP("output", "o", "text", "Output format: text or json")
+	DiffCmd.Flags().StringP("selector", "l", "", "Label selector of the deployed endpoints to compare. Eg. 'team=payments'")
+	DiffCmd.Flags().Bool("no-color", false, "Don't color the diff, which is only colored on terminals")
+	addProjectFlag(DiffCmd)
+	addVarFlag(DiffCmd)
+}
+
+func diffEndpoints(cmd *cobra.Command, args []string) {
+	configData, err := config.LoadConfig(config.TokenFile)
+	if err != nil {
+		fmt.Println("You need to login first.")
+		os.Exit(2)
+	}
+	dir, _ := cmd.Flags().GetString("dir")
+	format, _ := cmd.Flags().GetString("output")
+	selector, _ := cmd.Flags().GetString("selector")
+	noColor, _ := cmd.Flags().GetBool("no-color")
+	if format != "text" && format != "json" {
+		fmt.Printf("unknown output format %q, use text or json\n", format)
+		os.Exit(2)
+	}
+	vars, err := varFlags(cmd)
+	if err != nil {
+		fmt.Println("Invalid variable:", err)
+		os.Exit(2)
+	}
+
+	paths, err := findEndpointFiles(dir)
+	if err != nil {
+		fmt.Println("Error reading endpoint files:", err)
+		os.Exit(2)
+	}
+	projectID, err := projectFlag(cmd, configData)
+	if err != nil {
+		fmt.Println("Error finding project:", err)
+		os.Exit(2)
+	}
+	endpoints, err := fetchEndpoints(configData.Token, projectID)
+	if err != nil {
+		fmt.Println("Error fetching endpoints:", err)
+		os.Exit(2)
+	}
+	if endpoints, err = selectEndpoints(endpoints, selector); err != nil {
+		fmt.Println("Invalid selector:", err)
+		os.Exit(2)
+	}
+
+	drifts := compareEndpoints(paths, endpoints, vars)
+
+	if format == "json" {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(drifts); err != nil {
+			fmt.Println("Error printing the diff:", err)
+			os.Exit(2)
+		}
+	} else {
+		printDrifts(os.Stdout, drifts, !noColor && os.Getenv("NO_COLOR") == "" && isTerminal(os.Stdout))
+	}
+
+	os.Exit(driftExitCode(drifts))
+}
+
+// driftExitCode is 2 when a comparison failed, 1 on drift and 0 otherwise
+func driftExitCode(drifts []endpointDrift) int {
+	code := 0
+	for _, drift := range drifts {
+		switch drift.Status {
+		case driftError:
+			return 2
+		case driftUnchanged:
+		default:
+			code = 1
+		}
+	}
+	return code
+}
+
+// compareEndpoints pairs the endpoint files with the deployed endpoints and compares them
+func compareEndpoints(paths []string, endpoints []map[string]interface{}, vars map[string]string) []endpointDrift {
+	byID := make(map[string]map[string]interface{}, len(endpoints))
+	byRoute := make(map[string]map[string]interface{}, len(endpoints))
+	for _, endpoint := range endpoints {
+		byID[stringField(endpoint, "id")] = endpoint
+		if route := apiRoute(endpoint); route != "" {
+			byRoute[route] = endpoint
+		}
+	}
+
+	// An empty diff is printed as [] with -o json
+	drifts := []endpointDrift{}
+	paired := make(map[string]bool)
+	for _, filePath := range paths {
+		drift := endpointDrift{File: filePath}
+		payload, err := endpointPayloadFromFile(filePath, vars)
+		if err != nil {
+			drift.Status, drift.Error = driftError, err.Error()
+			drifts = append(drifts, drift)
+			continue
+		}
+
+		// The endpoint of the file in the state, the one it was pulled from, or else the one
+		// with its route
+		entry := stateEntry(filePath)
+		drift.LocallyEdited = editedSinceRecorded(filePath, entry)
+		deployed := byID[entry.ID]
+		if deployed == nil {
+			deployed = byID[pulledFromID(filePath)]
+		}
+		if deployed == nil {
+			deployed = byRoute[apiRoute(payload)]
+		}
+		if deployed == nil || paired[stringField(deployed, "id")] {
+			drift.Status = driftNotDeployed
+			drifts = append(drifts, drift)
+			continue
+		}
+		drift.ID, drift.MockURL = stringField(deployed, "id"), stringField(deployed, "endpointUrl")
+		paired[drift.ID] = true
+
+		if err := drift.compare(payload, deployed); err != nil {
+			drift.Status, drift.Error = driftError, err.Error()
+		}
+		drifts = append(drifts, drift)
+	}
+
+	for _, endpoint := range endpoints {
+		if id := stringField(endpoint, "id"); !paired[id] {
+			drifts = append(drifts, endpointDrift{ID: id, MockURL: stringField(endpoint, "endpointUrl"), Status: driftNoFile})
+		}
+	}
+	return drifts
+}
+
+var pulledHeader = regexp.MustCompile(`^# Pulled from endpoint (\S+)`)
+
+// pulledFromID returns the ID in the header comment of a pulled file
+func pulledFromID(filePath string) string {
+	content, err := utils.LoadFile(filePath)
+	if err != nil {
+		return ""
+	}
+	if match := pulledHeader.FindStringSubmatch(content); match != nil {
+		return match[1]
+	}
+	return ""
+}
+
+// apiRoute is the method and path of an endpoint of the API, or "" when it has no path
+func apiRoute(endpoint map[string]interface{}) string {
+	path := stringField(endpoint, "path")
+	if path == "" {
+		return ""
+	}
+	method := strings.ToUpper(stringField(endpoint, "method"))
+	if method == "" {
+		method = "GET"
+	}
+	return method + " " + path
+}
+
+// compare maps the payload of the file and the deployed endpoint to endpoint files, which
+// makes the fields of both comparable, and lists the fields that differ
+func (d *endpointDrift) compare(payload, deployed map[string]interface{}) error {
+	// The payload is read as the API would return it
+	encoded, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	var created map[string]interface{}
+	if err := json.Unmarshal(encoded, &created); err != nil {
+		return err
+	}
+
+	local, err := endpointFileFromAPI(created)
+	if err != nil {
+		return err
+	}
+	remote, err := endpointFileFromAPI(deployed)
+	if err != nil {
+		return err
+	}
+	d.local = flattenFields("", local["endpoint"])
+	d.remote = flattenFields("", remote["endpoint"])
+
+	// Expiries set with --ttl aren't part of the file
+	if _, ok := d.local["expiresAt"]; !ok {
+		delete(d.remote, "expiresAt")
+	}
+
+	d.Status = driftUnchanged
+	for _, field := range fieldNames(d.local, d.remote) {
+		local, remote := d.local[field], d.remote[field]
+		if !reflect.DeepEqual(local, remote) {
+			d.Changes = append(d.Changes, fieldChange{Field: field, Local: local, Remote: remote})
+			d.Status = driftChanged
+		}
+	}
+	return nil
+}
+
+// flattenFields maps the nested fields of a value to their dotted names. Lists are values.
+func flattenFields(prefix string, value interface{}) map[string]interface{} {
+	fields := make(map[string]interface{})
+	object, ok := value.(map[string]interface{})
+	if !ok {
+		fields[prefix] = value
+		return fields
+	}
+	for key, v := range object {
+		name := key
+		if prefix != "" {
+			name = prefix + "." + key
+		}
+		for field, fieldValue := range flattenFields(name, v) {
+			fields[field] = fieldValue
+		}
+	}
+	return fields
+}
+
+// fieldNames returns the sorted names of the fields of both sides
+func fieldNames(sides ...map[string]interface{}) []string {
+	seen := make(map[string]bool)
+	var names []string
+	for _, fields := range sides {
+		for name := range fields {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// fieldLines writes fields as sorted "name: value" lines, values in JSON
+func fieldLines(fields map[string]interface{}) []string {
+	lines := make([]string, 0, len(fields))
+	for _, name := range fieldNames(fields) {
+		value, _ := json.Marshal(fields[name])
+		lines = append(lines, name+": "+string(value)+"\n")
+	}
+	return lines
+}
+
+const (
+	colorReset = "\033[0m"
+	colorBold  = "\033[1m"
+	colorRed   = "\033[31m"
+	colorGreen = "\033[32m"
+	colorCyan  = "\033[36m"
+)
+
+// printDrifts writes a field-level unified diff of every drifted endpoint and a summary
+func printDrifts(w io.Writer, drifts []endpointDrift, color bool) {
+	drifted := 0
+	for _, drift := range drifts {
+		endpoint := "endpoint " + drift.ID
+		if drift.MockURL != "" {
+			endpoint += " (" + drift.MockURL + ")"
+		}
+
+		switch drift.Status {
+		case driftUnchanged:
+			continue
+		case driftError:
+			fmt.Fprintf(w, "%s: %s\n", drift.File, drift.Error)
+		case driftNotDeployed:
+			fmt.Fprintf(w, "%s: not deployed\n", drift.File)
+		case driftNoFile:
+			fmt.Fprintf(w, "%s: no endpoint file\n", endpoint)
+		case driftChanged:
+			fromFile := drift.File
+			if drift.LocallyEdited {
+				fromFile += " (edited locally)"
+			}
+			diff, _ := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+				A:        fieldLines(drift.local),
+				B:        fieldLines(drift.remote),
+				FromFile: fromFile,
+				ToFile:   endpoint,
+				Context:  3,
+			})
+			fmt.Fprint(w, colorDiff(diff, color))
+		}
+		drifted++
+	}
+
+	if drifted == 0 {
+		fmt.Fprintf(w, "No drift in %d endpoints\n", len(drifts))
+		return
+	}
+	fmt.Fprintf(w, "%d of %d endpoints drifted\n", drifted, len(drifts))
+}
+
+// colorDiff colors the lines of a unified diff
+func colorDiff(diff string, color bool) string {
+	if !color {
+		return diff
+	}
+	lines := strings.SplitAfter(diff, "\n")
+	for i, line := range lines {
+		var code string
+		switch {
+		case strings.HasPrefix(line, "---"), strings.HasPrefix(line, "+++"):
+			code = colorBold
+		case strings.HasPrefix(line, "@@"):
+			code = colorCyan
+		case strings.HasPrefix(line, "-"):
+			code = colorRed
+		case strings.HasPrefix(line, "+"):
+			code = colorGreen
+		default:
+			continue
+		}
+		lines[i] = code + strings.TrimSuffix(line, "\n") + colorReset + "\n"
+	}
+	return strings.Join(lines, "")
+}
+
+// isTerminal reports whether f is a terminal
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,134 @@
+package commands
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompareEndpoints(t *testing.T) {
+	dir := t.TempDir()
+
+	// A pulled file, then edited remotely
+	pulled := apiEndpoint()
+	pulledPath := filepath.Join(dir, "post-charges-id.yml")
+	_, err := pullEndpoint(pulled, pulledPath, false)
+	assert.NoError(t, err)
+
+	// A hand-written file matched by its route
+	routed := filepath.Join(dir, "users.yml")
+	assert.NoError(t, os.WriteFile(routed, []byte("apiVersion: v2\nendpoint:\n  path: /users\n  response:\n    body: '[]'\n"), 0644))
+
+	notDeployed := filepath.Join(dir, "orders.yml")
+	assert.NoError(t, os.WriteFile(notDeployed, []byte("apiVersion: v2\nendpoint:\n  path: /orders\n  response:\n    body: '[]'\n"), 0644))
+
+	invalid := filepath.Join(dir, "invalid.yml")
+	assert.NoError(t, os.WriteFile(invalid, []byte("apiVersion: v2\nendpoint:\n  response:\n    status: nope\n"), 0644))
+
+	deployed := apiEndpoint()
+	deployed["status"] = float64(500)
+	deployed["expiresAt"] = "2099-01-01T00:00:00Z"
+	users := map[string]interface{}{
+		"id": "e2", "method": "GET", "path": "/users", "status": float64(200),
+		"responseContentType": "application/json", "charset": "UTF-8", "responseBody": "[]",
+	}
+	orphan := map[string]interface{}{"id": "e3", "endpointUrl": "https://mock.example.com/e3"}
+
+	drifts := compareEndpoints([]string{pulledPath, routed, notDeployed, invalid}, []map[string]interface{}{deployed, users, orphan}, nil)
+	if !assert.Len(t, drifts, 5) {
+		return
+	}
+
+	assert.Equal(t, driftChanged, drifts[0].Status)
+	assert.Equal(t, "e1", drifts[0].ID)
+	// The expiry set with --ttl isn't drift
+	assert.Equal(t, []fieldChange{{Field: "response.status", Local: 201, Remote: 500}}, drifts[0].Changes)
+
+	assert.Equal(t, driftUnchanged, drifts[1].Status)
+	assert.Equal(t, "e2", drifts[1].ID)
+	assert.Equal(t, driftNotDeployed, drifts[2].Status)
+	assert.Equal(t, driftError, drifts[3].Status)
+	assert.Equal(t, endpointDrift{ID: "e3", MockURL: "https://mock.example.com/e3", Status: driftNoFile}, drifts[4])
+	assert.Equal(t, 2, driftExitCode(drifts))
+	assert.Equal(t, 1, driftExitCode(drifts[:3]))
+	assert.Equal(t, 0, driftExitCode(drifts[1:2]))
+
+	var out bytes.Buffer
+	printDrifts(&out, drifts[:3], false)
+	assert.Contains(t, out.String(), "--- "+pulledPath+"\n+++ endpoint e1 (https://mock.example.com/e1)\n")
+	assert.Contains(t, out.String(), "-response.status: 201\n+response.status: 500\n")
+	assert.Contains(t, out.String(), notDeployed+": not deployed\n")
+	assert.Contains(t, out.String(), "2 of 3 endpoints drifted\n")
+
+	assert.Equal(t, "\033[1m--- a\033[0m\n\033[31m-x\033[0m\n\033[32m+y\033[0m\n z\n", colorDiff("--- a\n-x\n+y\n z\n", true))
+	assert.Equal(t, "-x\n", colorDiff("-x\n", false))
+}
+
+func TestCompareVariableText(t *testing.T) {
+	t.Setenv("X", "leaked")
+	dir := t.TempDir()
+	deployed := apiEndpoint()
+	deployed["responseBody"] = `{"greeting":"Hello ${X}"}`
+	path := filepath.Join(dir, "post-charges-id.yml")
+	_, err := pullEndpoint(deployed, path, false)
+	assert.NoError(t, err)
+
+	// Files are read without printing, which would break -o json
+	missing := filepath.Join(dir, "missing.yml")
+	assert.NoError(t, os.WriteFile(missing, []byte("apiVersion: v2\nendpoint:\n  path: /missing\n  response:\n    body_file: nope.json\n"), 0644))
+	stdout := os.Stdout
+	r, w, err := os.Pipe()
+	assert.NoError(t, err)
+	os.Stdout = w
+	drifts := compareEndpoints([]string{path, missing}, []map[string]interface{}{deployed}, map[string]string{"X": "var"})
+	os.Stdout = stdout
+	assert.NoError(t, w.Close())
+	printed, err := io.ReadAll(r)
+	assert.NoError(t, err)
+
+	assert.Empty(t, string(printed))
+	if assert.Len(t, drifts, 2) {
+		assert.Equal(t, driftUnchanged, drifts[0].Status, drifts[0].Changes)
+		assert.Equal(t, driftError, drifts[1].Status)
+		assert.Contains(t, drifts[1].Error, "nope.json")
+	}
+}
+
+func TestCompareNoEndpoints(t *testing.T) {
+	// Scripts reading -o json get an empty list rather than null
+	content, err := json.Marshal(compareEndpoints(nil, nil, nil))
+	assert.NoError(t, err)
+	assert.Equal(t, "[]", string(content))
+}
+
+func TestCompareLocallyEdited(t *testing.T) {
+	// The temporary directory can be a symlink, as on macOS
+	dir, err := filepath.EvalSymlinks(t.TempDir())
+	assert.NoError(t, err)
+	chdir(t, dir)
+	assert.NoError(t, os.WriteFile("users.yml", []byte("apiVersion: v2\nendpoint:\n  path: /users\n  response:\n    body: '[]'\n"), 0644))
+	assert.NoError(t, recordEndpoint("users.yml", "e2", ""))
+	users := map[string]interface{}{
+		"id": "e2", "method": "GET", "path": "/users", "status": float64(200),
+		"responseContentType": "application/json", "charset": "UTF-8", "responseBody": "[]",
+	}
+
+	drifts := compareEndpoints([]string{"users.yml"}, []map[string]interface{}{users}, nil)
+	assert.Equal(t, driftUnchanged, drifts[0].Status)
+	assert.False(t, drifts[0].LocallyEdited)
+
+	// The file changed since it was created, rather than the endpoint
+	assert.NoError(t, os.WriteFile("users.yml", []byte("apiVersion: v2\nendpoint:\n  path: /users\n  response:\n    status: 201\n    body: '[]'\n"), 0644))
+	drifts = compareEndpoints([]string{"users.yml"}, []map[string]interface{}{users}, nil)
+	assert.Equal(t, driftChanged, drifts[0].Status)
+	assert.True(t, drifts[0].LocallyEdited)
+
+	var out bytes.Buffer
+	printDrifts(&out, drifts, false)
+	assert.Contains(t, out.String(), "--- users.yml (edited locally)\n+++ endpoint e2\n")
+}
@@ -0,0 +1,340 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/nicobistolfi/mockthis-cli/internal/utils"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// Endpoint files are expanded before they are validated. A value tagged !include, or a
+// mapping holding only a $ref, is replaced by the content of the file it names, relative to
+// the including file. A #/json/pointer after the file name selects a part of it. Then
+// ${NAME} and ${NAME:-default} are replaced in every value, looking NAME up in the --var
+// flags, the environment and the vars block of the file, in that order. $${NAME} is kept as
+// ${NAME}.
+
+var variablePattern = regexp.MustCompile(`\$(\$?)\{([A-Za-z_][A-Za-z0-9_]*)(:-[^}]*)?\}`)
+
+// expandError is an include or variable that can't be resolved, at a position of a file
+type expandError struct {
+	path   string
+	line   int
+	column int
+	err    error
+}
+
+func (e *expandError) Error() string {
+	return fmt.Sprintf("%s:%d:%d: %v", e.path, e.line, e.column, e.err)
+}
+
+func newExpandError(path string, node *yaml.Node, err error) *expandError {
+	return &expandError{path: path, line: node.Line, column: node.Column, err: err}
+}
+
+type expansion struct {
+	vars     map[string]string
+	fileVars map[string]string
+	// including lists the files being included, to catch files including themselves
+	including []string
+}
+
+// addVarFlag adds the --var flag of the commands reading endpoint files
+func addVarFlag(cmd *cobra.Command) {
+	cmd.Flags().StringArray("var", nil, "Set a ${NAME} variable of the endpoint files, as NAME=value")
+}
+
+// varFlags returns the variables set with --var
+func varFlags(cmd *cobra.Command) (map[string]string, error) {
+	flags, _ := cmd.Flags().GetStringArray("var")
+	vars := make(map[string]string, len(flags))
+	for _, flag := range flags {
+		name, value, ok := strings.Cut(flag, "=")
+		if !ok || !variablePattern.MatchString("${"+name+"}") {
+			return nil, fmt.Errorf("%q is not a NAME=value variable", flag)
+		}
+		vars[name] = value
+	}
+	return vars, nil
+}
+
+// expandEndpointFile resolves the includes and variables of the content of an endpoint file.
+// Content that doesn't parse is returned as is, for the parser to report.
+func expandEndpointFile(path, content string, vars map[string]string) (string, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(content), &doc); err != nil {
+		return content, nil
+	}
+
+	changed, err := expandDocument(&doc, path, vars)
+	if err != nil || !changed {
+		return content, err
+	}
+
+	expanded, err := encodeDocument(&doc, utils.IsJSON(content))
+	if err != nil {
+		return "", err
+	}
+	return string(expanded), nil
+}
+
+// expandDocument resolves the includes and variables of an endpoint file document in place
+// and removes its vars block. Included nodes take the position of the include, so errors
+// found later point at it. It reports whether the document changed.
+func expandDocument(doc *yaml.Node, path string, vars map[string]string) (bool, error) {
+	if doc.Kind != yaml.DocumentNode || len(doc.Content) == 0 {
+		return false, nil
+	}
+	root := doc.Content[0]
+	e := &expansion{vars: vars, fileVars: make(map[string]string), including: []string{absolutePath(path)}}
+
+	varsKey, block := removeMappingKey(root, "vars")
+	if varsKey != nil {
+		if block.Kind != yaml.MappingNode {
+			return false, newExpandError(path, varsKey, fmt.Errorf("vars is not a mapping of names to values"))
+		}
+		for i := 0; i+1 < len(block.Content); i += 2 {
+			name, value := block.Content[i], block.Content[i+1]
+			if value.Kind != yaml.ScalarNode {
+				return false, newExpandError(path, name, fmt.Errorf("variable %s is not a string, number or boolean", name.Value))
+			}
+			expanded, err := e.expand(value.Value)
+			if err != nil {
+				return false, newExpandError(path, value, err)
+			}
+			e.fileVars[name.Value] = expanded
+		}
+	}
+
+	included, err := e.include(root, path)
+	if err != nil {
+		return false, err
+	}
+	substituted, err := e.substitute(root, path)
+	if err != nil {
+		return false, err
+	}
+	return varsKey != nil || included || substituted, nil
+}
+
+// include replaces the includes under node by the nodes they name
+func (e *expansion) include(node *yaml.Node, path string) (bool, error) {
+	if ref, ok := includeRef(node); ok {
+		fragment, err := e.load(ref, path, node)
+		if err != nil {
+			return false, err
+		}
+		setPosition(fragment, node.Line, node.Column)
+		*node = *fragment
+		return true, nil
+	}
+
+	changed := false
+	for _, child := range node.Content {
+		included, err := e.include(child, path)
+		if err != nil {
+			return false, err
+		}
+		changed = changed || included
+	}
+	return changed, nil
+}
+
+// includeRef returns the file a node includes. $refs within the same document or to URLs
+// are left to JSON schemas.
+func includeRef(node *yaml.Node) (string, bool) {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		return node.Value, node.Tag == "!include"
+	case yaml.MappingNode:
+		if len(node.Content) != 2 || node.Content[0].Value != "$ref" || node.Content[1].Kind != yaml.ScalarNode {
+			return "", false
+		}
+		ref := node.Content[1].Value
+		return ref, !strings.HasPrefix(ref, "#") && !strings.Contains(ref, "://")
+	}
+	return "", false
+}
+
+// load reads the node ref names, relative to the file at path, with its own includes resolved
+func (e *expansion) load(ref, path string, site *yaml.Node) (*yaml.Node, error) {
+	file, pointer, _ := strings.Cut(ref, "#")
+	if file == "" {
+		return nil, newExpandError(path, site, fmt.Errorf("include %q names no file", ref))
+	}
+	if !filepath.IsAbs(file) {
+		file = filepath.Join(filepath.Dir(path), file)
+	}
+
+	absolute := absolutePath(file)
+	for _, including := range e.including {
+		if including == absolute {
+			return nil, newExpandError(path, site, fmt.Errorf("%s includes itself", file))
+		}
+	}
+
+	content, err := utils.LoadFile(file)
+	if err != nil {
+		return nil, newExpandError(path, site, err)
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(content), &doc); err != nil {
+		return nil, newExpandError(path, site, fmt.Errorf("%s: %v", file, err))
+	}
+	if len(doc.Content) == 0 {
+		return nil, newExpandError(path, site, fmt.Errorf("%s is empty", file))
+	}
+
+	e.including = append(e.including, absolute)
+	defer func() { e.including = e.including[:len(e.including)-1] }()
+	if _, err := e.include(doc.Content[0], file); err != nil {
+		return nil, err
+	}
+
+	fragment, err := pointerNode(doc.Content[0], pointer)
+	if err != nil {
+		return nil, newExpandError(path, site, fmt.Errorf("%s: %v", file, err))
+	}
+	return fragment, nil
+}
+
+// pointerNode returns the node a JSON pointer selects under root
+func pointerNode(root *yaml.Node, pointer string) (*yaml.Node, error) {
+	if pointer == "" || pointer == "/" {
+		return root, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("%q is not a JSON pointer", "#"+pointer)
+	}
+
+	node := root
+	for _, token := range strings.Split(pointer[1:], "/") {
+		token = strings.NewReplacer("~1", "/", "~0", "~").Replace(token)
+		for node.Kind == yaml.AliasNode && node.Alias != nil {
+			node = node.Alias
+		}
+
+		var next *yaml.Node
+		switch node.Kind {
+		case yaml.MappingNode:
+			_, next = mappingValue(node, token)
+		case yaml.SequenceNode:
+			if index, err := strconv.Atoi(token); err == nil && index >= 0 && index < len(node.Content) {
+				next = node.Content[index]
+			}
+		}
+		if next == nil {
+			return nil, fmt.Errorf("%s is not found", "#"+pointer)
+		}
+		node = next
+	}
+	return node, nil
+}
+
+func setPosition(node *yaml.Node, line, column int) {
+	node.Line, node.Column = line, column
+	for _, child := range node.Content {
+		setPosition(child, line, column)
+	}
+}
+
+// substitute replaces the variables of every scalar under node
+func (e *expansion) substitute(node *yaml.Node, path string) (bool, error) {
+	if node.Kind == yaml.ScalarNode {
+		if !strings.Contains(node.Value, "$") {
+			return false, nil
+		}
+		expanded, err := e.expand(node.Value)
+		if err != nil {
+			return false, newExpandError(path, node, err)
+		}
+		if expanded == node.Value {
+			return false, nil
+		}
+		node.Value = expanded
+		// A plain ${STATUS} is read as the number or boolean it expands to
+		if node.Style == 0 && node.Tag == "!!str" {
+			node.Tag = ""
+		}
+		return true, nil
+	}
+
+	changed := false
+	for _, child := range node.Content {
+		substituted, err := e.substitute(child, path)
+		if err != nil {
+			return false, err
+		}
+		changed = changed || substituted
+	}
+	return changed, nil
+}
+
+// expand replaces the variables of a string
+func (e *expansion) expand(s string) (string, error) {
+	var missing string
+	expanded := variablePattern.ReplaceAllStringFunc(s, func(match string) string {
+		groups := variablePattern.FindStringSubmatch(match)
+		if groups[1] != "" {
+			return match[1:]
+		}
+		value, ok := e.lookup(groups[2])
+		if groups[3] != "" && value == "" {
+			return strings.TrimPrefix(groups[3], ":-")
+		}
+		if !ok && missing == "" {
+			missing = groups[2]
+		}
+		return value
+	})
+	if missing != "" {
+		return "", fmt.Errorf("variable %s is not set, set it with --var %s=value, in the environment or in the vars block", missing, missing)
+	}
+	return expanded, nil
+}
+
+// escapeVariables escapes the variables of the strings of a value, keys included, as $${NAME},
+// for the value written to an endpoint file to be read back as it is
+func escapeVariables(value interface{}) interface{} {
+	switch v := value.(type) {
+	case string:
+		return variablePattern.ReplaceAllStringFunc(v, func(match string) string { return "$" + match })
+	case map[string]interface{}:
+		escaped := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			escaped[escapeVariables(key).(string)] = escapeVariables(item)
+		}
+		return escaped
+	case []interface{}:
+		escaped := make([]interface{}, len(v))
+		for i, item := range v {
+			escaped[i] = escapeVariables(item)
+		}
+		return escaped
+	}
+	return value
+}
+
+func (e *expansion) lookup(name string) (string, bool) {
+	if value, ok := e.vars[name]; ok {
+		return value, true
+	}
+	if value, ok := os.LookupEnv(name); ok {
+		return value, true
+	}
+	value, ok := e.fileVars[name]
+	return value, ok
+}
+
+func absolutePath(path string) string {
+	if absolute, err := filepath.Abs(path); err == nil {
+		return absolute
+	}
+	return path
+}
@@ -0,0 +1,121 @@
+package commands
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadEndpointFileExpansion(t *testing.T) {
+	t.Setenv("MOCKTHIS_TEST_TOKEN", "from-env")
+	t.Setenv("MOCKTHIS_TEST_HOST", "env.example.com")
+
+	dir := writeEndpointFiles(t, map[string]string{
+		"_shared/common.yml": `headers:
+  json:
+    X-Env: ${ENV:-dev}
+auth: !include auth.yml
+`,
+		"_shared/auth.yml": `type: bearer
+token: ${MOCKTHIS_TEST_TOKEN}
+`,
+		"users.yml": `apiVersion: v2
+vars:
+  status: 201
+  MOCKTHIS_TEST_HOST: vars.example.com
+endpoint:
+  auth:
+    $ref: _shared/common.yml#/auth
+  method: POST
+  response:
+    status: ${status}
+    headers: !include _shared/common.yml#/headers/json
+    schema:
+      type: object
+      definitions:
+        user: {type: object}
+      $ref: '#/definitions/user'
+    body: '{"host": "${MOCKTHIS_TEST_HOST}", "price": "$${price}", "region": "${REGION}"}'
+`,
+	})
+
+	endpoint, err := readEndpointFile(filepath.Join(dir, "users.yml"), map[string]string{"REGION": "eu"})
+	assert.NoError(t, err)
+
+	assert.Equal(t, map[string]interface{}{
+		"type":       "bearer",
+		"properties": map[string]interface{}{"token": "from-env"},
+	}, endpoint["auth"])
+
+	response := endpoint["response"].(map[string]interface{})
+	assert.Equal(t, "POST", response["method"])
+	assert.Equal(t, "201", response["status"])
+	assert.Equal(t, map[string]interface{}{"X-Env": "dev"}, response["headers"])
+	assert.Equal(t, "#/definitions/user", response["schema"].(map[string]interface{})["$ref"])
+	assert.Equal(t, `{"host": "env.example.com", "price": "${price}", "region": "eu"}`, response["body"])
+
+	// Fragments are not endpoint files
+	paths, err := findEndpointFiles(dir)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{filepath.Join(dir, "users.yml")}, paths)
+}
+
+func TestExpansionDiagnostics(t *testing.T) {
+	dir := writeEndpointFiles(t, map[string]string{
+		"missing-var.yml":  "endpoint:\n  response:\n    body: ${MOCKTHIS_TEST_UNSET}\n",
+		"missing-file.yml": "endpoint:\n  response:\n    headers: !include _headers.yml\n",
+		"cycle.yml":        "endpoint:\n  response:\n    headers:\n      $ref: _a.yml\n",
+		"_a.yml":           "$ref: _b.yml\n",
+		"_b.yml":           "$ref: _a.yml\n",
+		"_ok.yml":          "X-Example: ok\n",
+		"pointer.json":     `{"endpoint": {"response": {"headers": {"$ref": "_ok.yml#/missing"}}}}`,
+	})
+
+	diagnostics, files, err := lintPaths([]string{dir}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 4, files)
+
+	found := make(map[string]diagnostic)
+	for _, d := range diagnostics {
+		assert.Equal(t, ruleExpansion, d.Rule)
+		found[filepath.Base(d.File)] = d
+	}
+	assert.Len(t, found, 4)
+	assert.Equal(t, [2]int{3, 11}, [2]int{found["missing-var.yml"].Line, found["missing-var.yml"].Column})
+	assert.Contains(t, found["missing-var.yml"].Message, "variable MOCKTHIS_TEST_UNSET is not set")
+	assert.Equal(t, 3, found["missing-file.yml"].Line)
+	assert.Contains(t, found["_b.yml"].Message, "includes itself")
+	assert.Contains(t, found["pointer.json"].Message, "#/missing is not found")
+}
+
+func TestVarFlags(t *testing.T) {
+	cmd := &cobra.Command{}
+	addVarFlag(cmd)
+	assert.NoError(t, cmd.ParseFlags([]string{"--var", "TOKEN=a=b", "--var", "EMPTY="}))
+
+	vars, err := varFlags(cmd)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"TOKEN": "a=b", "EMPTY": ""}, vars)
+
+	cmd = &cobra.Command{}
+	addVarFlag(cmd)
+	assert.NoError(t, cmd.ParseFlags([]string{"--var", "not a name=x"}))
+	_, err = varFlags(cmd)
+	assert.Error(t, err)
+}
+
+func TestEscapeVariables(t *testing.T) {
+	e := &expansion{vars: map[string]string{"X": "set"}}
+	for _, s := range []string{"${X}", "$${X}", "$$${X}", "${X:-a} $ {X} $X", "${MISSING}", "no variables"} {
+		escaped := escapeVariables(s).(string)
+		expanded, err := e.expand(escaped)
+		assert.NoError(t, err, s)
+		assert.Equal(t, s, expanded, escaped)
+	}
+
+	assert.Equal(t, map[string]interface{}{
+		"$${KEY}": []interface{}{"$${X}", 1},
+	}, escapeVariables(map[string]interface{}{"${KEY}": []interface{}{"${X}", 1}}))
+}
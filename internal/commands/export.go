@@ -0,0 +1,119 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+
+	"github.com/nicobistolfi/mockthis-cli/internal/config"
+	"github.com/spf13/cobra"
+)
+
+// ExportCmd is the command to write many endpoints as endpoint files
+var ExportCmd = &cobra.Command{
+	Use:   "export [id, name or file...] | - | --all | -l <selector>",
+	Short: "Write many endpoints as endpoint files",
+	Long: `Write endpoints as endpoint files, given by ID, read from stdin with -, or selected with
+--all or a label selector with -l, the way delete selects endpoints. The files are written
+concurrently and named as pull names them, and the failures are reported at the end.
+
+Unlike pull, export doesn't record the files in the state of the directory or mark them as
+pulled, to hand endpoints over to another repository or account. Files that differ from the
+endpoint are only overwritten with --force.`,
+	Example: `  mockthis export --all --dir exported/
+  mockthis export -l team=payments --dir mocks/payments
+  mockthis list -o json | jq -r '.[].id' | mockthis export - --dir exported/`,
+	Args: cobra.ArbitraryArgs,
+	Run:  export,
+}
+
+func init() {
+	ExportCmd.Flags().String("dir", ".", "Directory to write the endpoint files to")
+	ExportCmd.Flags().Bool("force", false, "Overwrite files that differ from the exported endpoint")
+	addSelectionFlags(ExportCmd, "export", "l")
+}
+
+func export(cmd *cobra.Command, args []string) {
+	configData, err := config.LoadConfig(config.TokenFile)
+	if err != nil {
+		fmt.Println("You need to login first.")
+		return
+	}
+	dir, _ := cmd.Flags().GetString("dir")
+	force, _ := cmd.Flags().GetBool("force")
+
+	ids, err := bulkIDs(cmd, configData, args)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	if len(ids) == 0 {
+		fmt.Println("No endpoints to export")
+		return
+	}
+	endpoints, err := fetchEndpoints(configData.Token, "")
+	if err != nil {
+		fmt.Println("Error fetching endpoints:", err)
+		os.Exit(1)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		fmt.Println("Error creating directory:", err)
+		os.Exit(1)
+	}
+
+	files, failures := exportEndpoints(endpoints, ids, dir, force)
+	for _, id := range ids {
+		if file, ok := files[id]; ok {
+			fmt.Printf("%s %s -> %s\n", file.status, id, file.path)
+		}
+	}
+	printBulkSummary(os.Stdout, "Exported", len(ids), failures)
+	if len(failures) > 0 {
+		os.Exit(1)
+	}
+}
+
+// exportedFile is an endpoint file written by export
+type exportedFile struct {
+	path   string
+	status string
+}
+
+// exportEndpoints writes the endpoint files of the endpoints with the given IDs in dir through
+// runBulk, returning the files written by ID
+func exportEndpoints(endpoints []map[string]interface{}, ids []string, dir string, force bool) (map[string]exportedFile, []bulkFailure) {
+	// Names are taken in the order of ids, for the same endpoints to get the same files
+	names := make(map[string]bool)
+	paths := make(map[string]string, len(ids))
+	for _, id := range ids {
+		if endpoint := findEndpoint(endpoints, id); endpoint != nil {
+			paths[id] = filepath.Join(dir, pulledFileName(endpoint, names))
+		}
+	}
+
+	var mu sync.Mutex
+	files := make(map[string]exportedFile, len(ids))
+	// Writing files is not limited like requests to the API
+	options := bulkOptions{Workers: runtime.NumCPU()}
+	failures := runBulk(ids, options, progressWriter(), func(id string) error {
+		endpoint := findEndpoint(endpoints, id)
+		if endpoint == nil {
+			return fmt.Errorf("endpoint not found")
+		}
+		content, err := endpointFileContent(endpoint)
+		if err != nil {
+			return err
+		}
+		status, err := writeEndpointFile(paths[id], content, "Exported", force)
+		if err != nil {
+			return err
+		}
+		mu.Lock()
+		files[id] = exportedFile{path: paths[id], status: status}
+		mu.Unlock()
+		return nil
+	})
+	return files, failures
+}
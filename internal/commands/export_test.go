@@ -0,0 +1,45 @@
+package commands
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExportEndpoints(t *testing.T) {
+	dir := t.TempDir()
+	endpoints := []map[string]interface{}{
+		apiEndpoint(),
+		{"id": "e2", "method": "GET", "path": "/users"},
+	}
+
+	files, failures := exportEndpoints(endpoints, []string{"e1", "e2", "missing"}, dir, false)
+	if assert.Len(t, failures, 1) {
+		assert.Equal(t, "missing", failures[0].ID)
+	}
+	assert.Equal(t, map[string]exportedFile{
+		"e1": {path: filepath.Join(dir, "post-charges-id.yml"), status: "Exported"},
+		"e2": {path: filepath.Join(dir, "get-users.yml"), status: "Exported"},
+	}, files)
+
+	// Exported files are plain endpoint files, creating the same endpoint
+	content, err := os.ReadFile(files["e1"].path)
+	assert.NoError(t, err)
+	assert.NotContains(t, string(content), "# Pulled from endpoint")
+	payload, err := endpointPayloadFromFile(files["e1"].path, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "/charges/{id}", payload["path"])
+
+	// Files that changed are kept unless forced
+	assert.NoError(t, os.WriteFile(files["e2"].path, []byte("endpoint: {}\n"), 0644))
+	files, failures = exportEndpoints(endpoints, []string{"e1", "e2"}, dir, false)
+	assert.Equal(t, "Unchanged", files["e1"].status)
+	if assert.Len(t, failures, 1) {
+		assert.Equal(t, "e2", failures[0].ID)
+	}
+	files, failures = exportEndpoints(endpoints, []string{"e2"}, dir, true)
+	assert.Empty(t, failures)
+	assert.Equal(t, "Updated", files["e2"].status)
+}
@@ -0,0 +1,375 @@
+package commands
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Versions of the endpoint file format. v1 files have no apiVersion key, keep the method in
+// the response and nest auth credentials under "properties". v2 files move the method to the
+// endpoint and put the credentials of every auth type next to its type.
+const (
+	apiVersionV1      = "v1"
+	apiVersionV2      = "v2"
+	currentAPIVersion = apiVersionV2
+)
+
+// fileVersion returns the format version of a parsed endpoint file
+func fileVersion(data map[string]interface{}) string {
+	if version, ok := data["apiVersion"].(string); ok && version != "" {
+		return version
+	}
+	return apiVersionV1
+}
+
+// normalizeEndpointFile rewrites a validated endpoint file of any version in place into the
+// shape the rest of the CLI reads: the v1 shape, with credentials nested under "properties".
+func normalizeEndpointFile(data map[string]interface{}) error {
+	version := fileVersion(data)
+	if version != apiVersionV1 && version != apiVersionV2 {
+		return fmt.Errorf("unsupported apiVersion %q, supported versions are %s and %s", version, apiVersionV1, apiVersionV2)
+	}
+	delete(data, "apiVersion")
+
+	endpoint, ok := data["endpoint"].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+
+	if auth, ok := endpoint["auth"].(map[string]interface{}); ok {
+		nestCredentials(auth)
+	}
+
+	if version == apiVersionV2 {
+		response, _ := endpoint["response"].(map[string]interface{})
+		if response == nil {
+			response = make(map[string]interface{})
+			endpoint["response"] = response
+		}
+		if method, ok := endpoint["method"]; ok {
+			response["method"] = method
+			delete(endpoint, "method")
+		}
+		for _, r := range endpointResponses(endpoint) {
+			if status, ok := r["status"]; ok {
+				r["status"] = fmt.Sprintf("%v", status)
+			}
+		}
+	}
+	return nil
+}
+
+// nestCredentials moves the credentials next to the auth type under "properties"
+func nestCredentials(auth map[string]interface{}) {
+	properties, _ := auth["properties"].(map[string]interface{})
+	if properties == nil {
+		properties = make(map[string]interface{})
+	}
+	for key, value := range auth {
+		if key == "type" || key == "properties" {
+			continue
+		}
+		if _, ok := properties[key]; !ok {
+			properties[key] = value
+		}
+		delete(auth, key)
+	}
+	auth["properties"] = properties
+}
+
+// endpointResponses returns the response of an endpoint and the responses of its scenario
+func endpointResponses(endpoint map[string]interface{}) []map[string]interface{} {
+	var responses []map[string]interface{}
+	if response, ok := endpoint["response"].(map[string]interface{}); ok {
+		responses = append(responses, response)
+	}
+	if scenario, ok := endpoint["scenario"].(map[string]interface{}); ok {
+		entries, _ := scenario["responses"].([]interface{})
+		for _, entry := range entries {
+			if entryMap, ok := entry.(map[string]interface{}); ok {
+				if response, ok := entryMap["response"].(map[string]interface{}); ok {
+					responses = append(responses, response)
+				}
+			}
+		}
+	}
+	return responses
+}
+
+// deepCopyMap copies parsed endpoint file data so it can be normalized without changing it
+func deepCopyMap(data map[string]interface{}) map[string]interface{} {
+	copied := make(map[string]interface{}, len(data))
+	for key, value := range data {
+		copied[key] = deepCopyValue(value)
+	}
+	return copied
+}
+
+func deepCopyValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return deepCopyMap(v)
+	case []interface{}:
+		copied := make([]interface{}, len(v))
+		for i, item := range v {
+			copied[i] = deepCopyValue(item)
+		}
+		return copied
+	}
+	return value
+}
+
+// migrateDocument rewrites a v1 endpoint file document as a v2 one, keeping comments and the
+// order of keys. It reports false when the document is already a v2 file.
+func migrateDocument(doc *yaml.Node) (bool, error) {
+	if doc.Kind != yaml.DocumentNode || len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return false, fmt.Errorf("the file is not a YAML or JSON object")
+	}
+	file := doc.Content[0]
+
+	if _, version := mappingValue(file, "apiVersion"); version != nil {
+		switch version.Value {
+		case apiVersionV2:
+			return false, nil
+		case apiVersionV1:
+			removeMappingKey(file, "apiVersion")
+		default:
+			return false, fmt.Errorf("unsupported apiVersion %q", version.Value)
+		}
+	}
+
+	_, endpoint := mappingValue(file, "endpoint")
+	if endpoint == nil || endpoint.Kind != yaml.MappingNode {
+		return false, fmt.Errorf("the endpoint key is not found")
+	}
+
+	// The method moves from the response to the endpoint, before the response
+	responseIndex, response := mappingValue(endpoint, "response")
+	if response != nil && response.Kind == yaml.MappingNode {
+		if key, value := removeMappingKey(response, "method"); key != nil {
+			insertMappingKey(endpoint, responseIndex, key, value)
+		}
+	}
+
+	// Scenario responses inherit the method of the endpoint
+	if _, scenario := mappingValue(endpoint, "scenario"); scenario != nil {
+		if _, responses := mappingValue(scenario, "responses"); responses != nil {
+			for _, entry := range responses.Content {
+				if _, override := mappingValue(entry, "response"); override != nil {
+					removeMappingKey(override, "method")
+				}
+			}
+		}
+	}
+
+	// Credentials nested under properties move next to the auth type
+	if _, auth := mappingValue(endpoint, "auth"); auth != nil && auth.Kind == yaml.MappingNode {
+		if index, properties := mappingValue(auth, "properties"); properties != nil && properties.Kind == yaml.MappingNode {
+			key, _ := removeMappingKey(auth, "properties")
+			for i := 0; i+1 < len(properties.Content); i += 2 {
+				if _, existing := mappingValue(auth, properties.Content[i].Value); existing != nil {
+					continue
+				}
+				if i == 0 {
+					properties.Content[i].HeadComment = joinComments(key.HeadComment, properties.Content[i].HeadComment)
+				}
+				insertMappingKey(auth, index, properties.Content[i], properties.Content[i+1])
+				index += 2
+			}
+		}
+	}
+
+	// apiVersion comes first, taking over the comment heading the file
+	versionKey := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: "apiVersion"}
+	versionValue := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: apiVersionV2}
+	if len(file.Content) > 0 {
+		versionKey.HeadComment = file.Content[0].HeadComment
+		file.Content[0].HeadComment = ""
+	}
+	insertMappingKey(file, 0, versionKey, versionValue)
+
+	return true, nil
+}
+
+// mappingValue returns the index of key in a mapping node and its value node
+func mappingValue(mapping *yaml.Node, key string) (int, *yaml.Node) {
+	if mapping == nil || mapping.Kind != yaml.MappingNode {
+		return -1, nil
+	}
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return i, mapping.Content[i+1]
+		}
+	}
+	return -1, nil
+}
+
+func removeMappingKey(mapping *yaml.Node, key string) (*yaml.Node, *yaml.Node) {
+	index, value := mappingValue(mapping, key)
+	if value == nil {
+		return nil, nil
+	}
+	keyNode := mapping.Content[index]
+	mapping.Content = append(mapping.Content[:index], mapping.Content[index+2:]...)
+	return keyNode, value
+}
+
+func insertMappingKey(mapping *yaml.Node, index int, key, value *yaml.Node) {
+	if index < 0 || index > len(mapping.Content) {
+		index = len(mapping.Content)
+	}
+	mapping.Content = append(mapping.Content[:index], append([]*yaml.Node{key, value}, mapping.Content[index:]...)...)
+}
+
+func joinComments(comments ...string) string {
+	var nonEmpty []string
+	for _, comment := range comments {
+		if comment != "" {
+			nonEmpty = append(nonEmpty, comment)
+		}
+	}
+	return strings.Join(nonEmpty, "\n")
+}
+
+// encodeDocument writes a document back in the syntax of the file it was read from
+func encodeDocument(doc *yaml.Node, asJSON bool) ([]byte, error) {
+	if asJSON {
+		var buf bytes.Buffer
+		if err := writeJSONNode(&buf, doc.Content[0], ""); err != nil {
+			return nil, err
+		}
+		buf.WriteString("\n")
+		return buf.Bytes(), nil
+	}
+
+	unmask := maskAstralRunes(doc)
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	err := encoder.Encode(doc)
+	if err == nil {
+		err = encoder.Close()
+	}
+	encoded := unmask(buf.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	return encoded, nil
+}
+
+// maskAstralRunes swaps the characters outside the Basic Multilingual Plane of the scalars
+// of a document, such as emoji, for private use characters it doesn't use. yaml.v3 would
+// write them as escapes in double quotes, changing scalars the caller didn't touch. The
+// returned function puts the scalars back and turns the characters back in encoded output.
+func maskAstralRunes(doc *yaml.Node) func([]byte) []byte {
+	var scalars []*yaml.Node
+	used := make(map[rune]bool)
+	var walk func(node *yaml.Node)
+	walk = func(node *yaml.Node) {
+		for _, text := range []string{node.Value, node.HeadComment, node.LineComment, node.FootComment} {
+			for _, r := range text {
+				used[r] = true
+			}
+		}
+		if node.Kind == yaml.ScalarNode && strings.IndexFunc(node.Value, isAstral) >= 0 {
+			scalars = append(scalars, node)
+		}
+		for _, child := range node.Content {
+			walk(child)
+		}
+	}
+	walk(doc)
+	if len(scalars) == 0 {
+		return func(encoded []byte) []byte { return encoded }
+	}
+
+	masks := make(map[rune]rune)
+	next := rune(0xE000)
+	originals := make([]string, len(scalars))
+	for i, node := range scalars {
+		originals[i] = node.Value
+		node.Value = strings.Map(func(r rune) rune {
+			if !isAstral(r) {
+				return r
+			}
+			if _, ok := masks[r]; !ok {
+				for used[next] {
+					next++
+				}
+				masks[r] = next
+				used[next] = true
+			}
+			return masks[r]
+		}, node.Value)
+	}
+
+	return func(encoded []byte) []byte {
+		for i, node := range scalars {
+			node.Value = originals[i]
+		}
+		for r, mask := range masks {
+			encoded = bytes.ReplaceAll(encoded, []byte(string(mask)), []byte(string(r)))
+		}
+		return encoded
+	}
+}
+
+func isAstral(r rune) bool {
+	return r > 0xFFFF
+}
+
+// writeJSONNode writes a node as indented JSON, keeping the order of keys
+func writeJSONNode(buf *bytes.Buffer, node *yaml.Node, indent string) error {
+	switch node.Kind {
+	case yaml.MappingNode, yaml.SequenceNode:
+		open, close, step := "{", "}", 2
+		if node.Kind == yaml.SequenceNode {
+			open, close, step = "[", "]", 1
+		}
+		if len(node.Content) == 0 {
+			buf.WriteString(open + close)
+			return nil
+		}
+		buf.WriteString(open + "\n")
+		inner := indent + "  "
+		for i := 0; i < len(node.Content); i += step {
+			buf.WriteString(inner)
+			if step == 2 {
+				key, _ := json.Marshal(node.Content[i].Value)
+				buf.Write(key)
+				buf.WriteString(": ")
+			}
+			if err := writeJSONNode(buf, node.Content[i+step-1], inner); err != nil {
+				return err
+			}
+			if i+step < len(node.Content) {
+				buf.WriteString(",")
+			}
+			buf.WriteString("\n")
+		}
+		buf.WriteString(indent + close)
+	case yaml.ScalarNode:
+		switch node.Tag {
+		case "!!int", "!!float":
+			if _, err := strconv.ParseFloat(node.Value, 64); err == nil {
+				buf.WriteString(node.Value)
+				return nil
+			}
+		case "!!bool", "!!null":
+			buf.WriteString(node.Value)
+			return nil
+		}
+		value, _ := json.Marshal(node.Value)
+		buf.Write(value)
+	case yaml.AliasNode:
+		return writeJSONNode(buf, node.Alias, indent)
+	default:
+		return fmt.Errorf("unsupported YAML node at line %d", node.Line)
+	}
+	return nil
+}
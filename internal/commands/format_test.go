@@ -0,0 +1,189 @@
+package commands
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/nicobistolfi/mockthis-cli/internal/utils"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+)
+
+const v1File = `# Users endpoint
+endpoint:
+  auth:
+    type: apiKey
+    # Sent by every client
+    properties:
+      name: X-API-Key
+      value: secret
+      in: header
+  response:
+    method: POST
+    status: "201"
+    body: '{"id": 1}'
+`
+
+const v2File = `# Users endpoint
+apiVersion: v2
+endpoint:
+  auth:
+    type: apiKey
+    # Sent by every client
+    name: X-API-Key
+    value: secret
+    in: header
+  method: POST
+  response:
+    status: "201"
+    body: '{"id": 1}'
+`
+
+func TestMigrateKeepsNonASCII(t *testing.T) {
+	var doc yaml.Node
+	assert.NoError(t, yaml.Unmarshal([]byte(`endpoint:
+  response:
+    method: GET
+    # Greets in every language 🌍
+    body: Hello, World! 🌎 こんにちは
+    headers:
+      X-Emoji: "🚀"
+      X-Private: "\ue000"
+`), &doc))
+
+	changed, err := migrateDocument(&doc)
+	assert.NoError(t, err)
+	assert.True(t, changed)
+
+	// Scalars migrate doesn't change are written back as they were
+	migrated, err := encodeDocument(&doc, false)
+	assert.NoError(t, err)
+	assert.Equal(t, `apiVersion: v2
+endpoint:
+  method: GET
+  response:
+    # Greets in every language 🌍
+    body: Hello, World! 🌎 こんにちは
+    headers:
+      X-Emoji: "🚀"
+      X-Private: "`+"\ue000"+`"
+`, string(migrated))
+
+	// The document itself is left unmasked
+	_, endpoint := mappingValue(doc.Content[0], "endpoint")
+	_, response := mappingValue(endpoint, "response")
+	_, body := mappingValue(response, "body")
+	assert.Equal(t, "Hello, World! 🌎 こんにちは", body.Value)
+}
+
+func TestMigrateDocument(t *testing.T) {
+	var doc yaml.Node
+	assert.NoError(t, yaml.Unmarshal([]byte(v1File), &doc))
+
+	changed, err := migrateDocument(&doc)
+	assert.NoError(t, err)
+	assert.True(t, changed)
+
+	migrated, err := encodeDocument(&doc, false)
+	assert.NoError(t, err)
+	assert.Equal(t, v2File, string(migrated))
+
+	// Migrating again changes nothing
+	changed, err = migrateDocument(&doc)
+	assert.NoError(t, err)
+	assert.False(t, changed)
+}
+
+func TestMigrateFileJSON(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "users.json")
+	content := `{"endpoint": {"auth": {"type": "bearer", "token": "t"}, "response": {"method": "GET", "status": "200", "headers": {"X-Count": "2"}}}}`
+	assert.NoError(t, os.WriteFile(path, []byte(content), 0644))
+
+	changed, diff, err := migrateFile(path, true, nil)
+	assert.NoError(t, err)
+	assert.True(t, changed)
+	assert.Contains(t, diff, `+  "apiVersion": "v2",`)
+	unchanged, _ := os.ReadFile(path)
+	assert.Equal(t, content, string(unchanged))
+
+	_, _, err = migrateFile(path, false, nil)
+	assert.NoError(t, err)
+	migrated, _ := os.ReadFile(path)
+	assert.Equal(t, `{
+  "apiVersion": "v2",
+  "endpoint": {
+    "auth": {
+      "type": "bearer",
+      "token": "t"
+    },
+    "method": "GET",
+    "response": {
+      "status": "200",
+      "headers": {
+        "X-Count": "2"
+      }
+    }
+  }
+}
+`, string(migrated))
+}
+
+// Every example reads the same before and after its migration
+func TestMigrateExamples(t *testing.T) {
+	paths, err := filepath.Glob("../../examples/*.yml")
+	assert.NoError(t, err)
+	assert.NotEmpty(t, paths)
+
+	for _, path := range paths {
+		t.Run(filepath.Base(path), func(t *testing.T) {
+			content, err := utils.LoadFile(path)
+			assert.NoError(t, err)
+
+			var doc yaml.Node
+			assert.NoError(t, yaml.Unmarshal([]byte(content), &doc))
+			_, err = migrateDocument(&doc)
+			assert.NoError(t, err)
+			migrated, err := encodeDocument(&doc, false)
+			assert.NoError(t, err)
+
+			v1, err := utils.ParseYAML(v1Version(t, content))
+			assert.NoError(t, err)
+			v2, err := utils.ParseYAML(string(migrated))
+			assert.NoError(t, err)
+			assert.NoError(t, utils.ValidateAgainstSchema(v2, ENDPOINT_SCHEMA))
+			assert.Equal(t, apiVersionV2, fileVersion(v2))
+
+			assert.NoError(t, normalizeEndpointFile(v1))
+			assert.NoError(t, normalizeEndpointFile(v2))
+			assert.Equal(t, v1, v2)
+		})
+	}
+}
+
+// v1Version downgrades an example, as examples are kept in the current version
+func v1Version(t *testing.T, content string) string {
+	var doc yaml.Node
+	assert.NoError(t, yaml.Unmarshal([]byte(content), &doc))
+	file := doc.Content[0]
+	if _, version := mappingValue(file, "apiVersion"); version == nil {
+		return content
+	}
+	removeMappingKey(file, "apiVersion")
+	_, endpoint := mappingValue(file, "endpoint")
+	_, response := mappingValue(endpoint, "response")
+	if key, value := removeMappingKey(endpoint, "method"); key != nil {
+		insertMappingKey(response, 0, key, value)
+	}
+	if _, auth := mappingValue(endpoint, "auth"); auth != nil {
+		properties := &yaml.Node{Kind: yaml.MappingNode}
+		for len(auth.Content) > 2 {
+			properties.Content = append(properties.Content, auth.Content[2:4]...)
+			auth.Content = append(auth.Content[:2], auth.Content[4:]...)
+		}
+		insertMappingKey(auth, 2, &yaml.Node{Kind: yaml.ScalarNode, Value: "properties"}, properties)
+	}
+	v1, err := encodeDocument(&doc, false)
+	assert.NoError(t, err)
+	return string(v1)
+}
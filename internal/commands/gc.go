@@ -0,0 +1,156 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/nicobistolfi/mockthis-cli/internal/config"
+	"github.com/nicobistolfi/mockthis-cli/internal/utils"
+	"github.com/spf13/cobra"
+)
+
+// GCCmd is the command to delete expired and stale endpoints
+var GCCmd = &cobra.Command{
+	Use:   "gc",
+	Short: "Delete expired and stale endpoints",
+	Long: `Delete the endpoints of the project past their expiry, and with --older-than the endpoints
+created longer ago than a duration. -l only collects the endpoints matching a label selector.
+
+The endpoints are listed before asking for confirmation, and --dry-run only lists them.`,
+	Example: `  mockthis gc --dry-run
+  mockthis gc --older-than 7d -l created-by=ci --yes
+  mockthis gc -A --older-than 30d`,
+	Args: cobra.NoArgs,
+	Run:  collectGarbage,
+}
+
+func init() {
+	GCCmd.Flags().String("older-than", "", "Also delete the endpoints created longer ago than a duration in s, m, h, d or w. Eg. '7d'")
+	GCCmd.Flags().StringP("selector", "l", "", "Label selector of the endpoints to collect. Eg. 'created-by=ci'")
+	GCCmd.Flags().Bool("dry-run", false, "List the endpoints that would be deleted without deleting them")
+	GCCmd.Flags().BoolP("yes", "y", false, "Delete without asking for confirmation")
+	addProjectFlag(GCCmd)
+	GCCmd.Flags().BoolP("all-projects", "A", false, "Collect the endpoints of every project")
+	addBulkFlags(GCCmd)
+}
+
+// staleEndpoint is an endpoint gc deletes and the reason it is stale
+type staleEndpoint struct {
+	endpoint map[string]interface{}
+	reason   string
+}
+
+func collectGarbage(cmd *cobra.Command, args []string) {
+	configData, err := config.LoadConfig(config.TokenFile)
+	if err != nil {
+		fmt.Println("You need to login first.")
+		return
+	}
+
+	var olderThan time.Duration
+	if value, _ := cmd.Flags().GetString("older-than"); value != "" {
+		if olderThan, err = utils.ParseDuration(value); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	}
+	selector, _ := cmd.Flags().GetString("selector")
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+	yes, _ := cmd.Flags().GetBool("yes")
+	options, err := bulkFlags(cmd)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	projectID := ""
+	if allProjects, _ := cmd.Flags().GetBool("all-projects"); !allProjects {
+		if projectID, err = projectFlag(cmd, configData); err != nil {
+			fmt.Println("Error finding project:", err)
+			os.Exit(1)
+		}
+	}
+	endpoints, err := fetchEndpoints(configData.Token, projectID)
+	if err != nil {
+		fmt.Println("Error fetching endpoints:", err)
+		os.Exit(1)
+	}
+	if endpoints, err = selectEndpoints(endpoints, selector); err != nil {
+		fmt.Println("Invalid selector:", err)
+		os.Exit(1)
+	}
+
+	stale := staleEndpoints(endpoints, time.Now(), olderThan)
+	if len(stale) == 0 {
+		fmt.Println("No endpoints to collect")
+		return
+	}
+
+	ids := make([]string, 0, len(stale))
+	for _, s := range stale {
+		fmt.Printf("  %v %v (%s)\n", s.endpoint["id"], s.endpoint["endpointUrl"], s.reason)
+		id, _ := s.endpoint["id"].(string)
+		ids = append(ids, id)
+	}
+	if dryRun {
+		fmt.Printf("%d endpoints would be deleted\n", len(ids))
+		return
+	}
+	if !yes && !confirm(fmt.Sprintf("Delete these %d endpoints?", len(ids))) {
+		fmt.Println("Aborted")
+		return
+	}
+
+	failures := deleteEndpoints(configData.Token, ids, options)
+	if err := forgetEndpoints(deletedIDs(ids, failures), nil); err != nil {
+		fmt.Println("Error updating the state:", err)
+	}
+	printBulkSummary(os.Stdout, "Deleted", len(ids), failures)
+	if len(failures) > 0 {
+		os.Exit(1)
+	}
+}
+
+// staleEndpoints returns the endpoints expired at now, and when olderThan is not 0 those
+// created before now minus olderThan, oldest first. Endpoints without a valid time for a
+// rule are kept by it.
+func staleEndpoints(endpoints []map[string]interface{}, now time.Time, olderThan time.Duration) []staleEndpoint {
+	var stale []staleEndpoint
+	for _, endpoint := range endpoints {
+		if expiresAt, ok := endpointTime(endpoint, "expiresAt"); ok && !expiresAt.After(now) {
+			stale = append(stale, staleEndpoint{endpoint, "expired " + formatAge(now.Sub(expiresAt)) + " ago"})
+			continue
+		}
+		if createdAt, ok := endpointTime(endpoint, "createdAt"); ok && olderThan > 0 && now.Sub(createdAt) > olderThan {
+			stale = append(stale, staleEndpoint{endpoint, "created " + formatAge(now.Sub(createdAt)) + " ago"})
+		}
+	}
+
+	sort.SliceStable(stale, func(i, j int) bool {
+		a, _ := endpointTime(stale[i].endpoint, "createdAt")
+		b, _ := endpointTime(stale[j].endpoint, "createdAt")
+		return a.Before(b)
+	})
+	return stale
+}
+
+// endpointTime returns an RFC 3339 time field of an endpoint
+func endpointTime(endpoint map[string]interface{}, field string) (time.Time, bool) {
+	value, _ := endpoint[field].(string)
+	t, err := time.Parse(time.RFC3339, value)
+	return t, err == nil
+}
+
+// formatAge writes a duration in its largest whole unit, from minutes to days
+func formatAge(d time.Duration) string {
+	switch {
+	case d >= 24*time.Hour:
+		return fmt.Sprintf("%dd", d/(24*time.Hour))
+	case d >= time.Hour:
+		return fmt.Sprintf("%dh", d/time.Hour)
+	default:
+		return fmt.Sprintf("%dm", d/time.Minute)
+	}
+}
@@ -0,0 +1,78 @@
+package commands
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStaleEndpoints(t *testing.T) {
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	endpoints := []map[string]interface{}{
+		{"id": "fresh", "createdAt": "2026-03-09T12:00:00Z"},
+		{"id": "old", "createdAt": "2026-02-01T12:00:00Z"},
+		{"id": "expired", "createdAt": "2026-03-10T08:00:00Z", "expiresAt": "2026-03-10T10:00:00Z"},
+		{"id": "expiring", "createdAt": "2026-01-01T12:00:00Z", "expiresAt": "2026-03-11T00:00:00Z"},
+		{"id": "unknown"},
+	}
+
+	ids := func(stale []staleEndpoint) []interface{} {
+		var ids []interface{}
+		for _, s := range stale {
+			ids = append(ids, s.endpoint["id"])
+		}
+		return ids
+	}
+
+	stale := staleEndpoints(endpoints, now, 0)
+	assert.Equal(t, []interface{}{"expired"}, ids(stale))
+	assert.Equal(t, "expired 2h ago", stale[0].reason)
+
+	// An expiry in the future doesn't keep old endpoints from being collected
+	stale = staleEndpoints(endpoints, now, 7*24*time.Hour)
+	assert.Equal(t, []interface{}{"expiring", "old", "expired"}, ids(stale))
+	assert.Equal(t, "created 37d ago", stale[1].reason)
+}
+
+func TestProcessExpiry(t *testing.T) {
+	newCmd := func(args ...string) *cobra.Command {
+		cmd := &cobra.Command{}
+		cmd.Flags().String("ttl", "", "")
+		cmd.Flags().String("expires-at", "", "")
+		cmd.Flags().String("body", "", "")
+		assert.NoError(t, cmd.ParseFlags(args))
+		return cmd
+	}
+
+	data := map[string]interface{}{}
+	assert.NoError(t, processExpiry(newCmd("--ttl", "2h"), data))
+	expiresAt, err := time.Parse(time.RFC3339, data["expiresAt"].(string))
+	assert.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(2*time.Hour), expiresAt, time.Minute)
+
+	assert.Error(t, processExpiry(newCmd("--ttl", "2h", "--expires-at", "2099-01-01T00:00:00Z"), map[string]interface{}{}))
+	assert.Error(t, processExpiry(newCmd("--ttl", "soon"), map[string]interface{}{}))
+	assert.Error(t, processExpiry(newCmd(), map[string]interface{}{"expiresAt": "2001-01-01T00:00:00Z"}))
+	assert.Error(t, processExpiry(newCmd(), map[string]interface{}{"expiresAt": "tomorrow"}))
+
+	// The expiry of a file is overridden by --ttl
+	path := filepath.Join(t.TempDir(), "expiring.yml")
+	content := "endpoint:\n  expiresAt: 2099-01-01T00:00:00Z\n  response:\n    body: expiring\n"
+	assert.NoError(t, os.WriteFile(path, []byte(content), 0644))
+
+	cmd := newCmd()
+	assert.NoError(t, loadFromFile(path, cmd))
+	value, _ := cmd.Flags().GetString("expires-at")
+	assert.Equal(t, "2099-01-01T00:00:00Z", value)
+
+	cmd = newCmd("--ttl", "1d")
+	assert.NoError(t, loadFromFile(path, cmd))
+	data = loadFromFlags(cmd)
+	assert.NoError(t, processExpiry(cmd, data))
+	expiresAt, _ = time.Parse(time.RFC3339, data["expiresAt"].(string))
+	assert.WithinDuration(t, time.Now().Add(24*time.Hour), expiresAt, time.Minute)
+}
@@ -1,27 +1,32 @@
 package commands
 
 import (
-	"encoding/json"
+	"encoding/base64"
 	"fmt"
-	"io"
 	"net/http"
+	"net/url"
+	"os"
 
 	"github.com/nicobistolfi/mockthis-cli/internal/config"
+	"github.com/nicobistolfi/mockthis-cli/internal/output"
 	"github.com/spf13/cobra"
 )
 
 // GetEndpointCmd is the command to get details of an existing mock endpoint
 var GetEndpointCmd = &cobra.Command{
-	Use:   "get [id or mockIdentifier]",
+	Use:   "get [id, mockIdentifier, name or endpoint file]",
 	Short: "Get details of an endpoint",
 	Args:  cobra.ExactArgs(1),
 	Run:   getEndpointCmd,
 }
 
 var outputFormat string
+var saveBodyPath string
 
 func init() {
-	GetEndpointCmd.Flags().StringVarP(&outputFormat, "output", "o", "list", "Output format: list, table, or json")
+	GetEndpointCmd.Flags().StringVarP(&outputFormat, "output", "o", "list", "Output format: list, "+output.Formats)
+	GetEndpointCmd.Flags().StringVar(&saveBodyPath, "save-body", "", "Path to save the stored response body to, decoded if it is binary")
+	addProjectFlag(GetEndpointCmd)
 }
 
 func getEndpointCmd(cmd *cobra.Command, args []string) {
@@ -30,62 +35,46 @@ func getEndpointCmd(cmd *cobra.Command, args []string) {
 		fmt.Println("You need to login first.")
 		return
 	}
-	token := configData.Token
-
-	req, _ := http.NewRequest("GET", config.BaseURL+"/endpoints", nil)
-	req.Header.Set("Authorization", "Bearer "+token)
-
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	projectID, err := projectFlag(cmd, configData)
 	if err != nil {
-		fmt.Println("Error fetching endpoints:", err)
-		return
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		fmt.Println("Failed to fetch endpoints. Status:", resp.Status)
+		fmt.Println("Error finding project:", err)
 		return
 	}
-
-	body, err := io.ReadAll(resp.Body)
+	endpoints, err := fetchEndpoints(configData.Token, projectID)
 	if err != nil {
-		fmt.Println("Error reading response body:", err)
+		fmt.Println("Error fetching endpoints:", err)
 		return
 	}
 
-	var endpoints []map[string]interface{}
-	err = json.Unmarshal(body, &endpoints)
+	id, err := resolveEndpointRef(args[0])
 	if err != nil {
-		fmt.Println("Error parsing JSON:", err)
+		fmt.Println(err)
 		return
 	}
-
-	idOrMockIdentifier := args[0]
-	var targetEndpoint map[string]interface{}
-
-	for _, endpoint := range endpoints {
-		if endpoint["id"] == idOrMockIdentifier || endpoint["mockIdentifier"] == idOrMockIdentifier {
-			targetEndpoint = endpoint
-			break
-		}
-	}
-
+	targetEndpoint := findEndpoint(endpoints, id)
 	if targetEndpoint == nil {
 		fmt.Println("Endpoint not found.")
 		return
 	}
 
+	setBodyEncoding(targetEndpoint)
+	if saveBodyPath != "" {
+		if err := saveResponseBody(targetEndpoint, saveBodyPath); err != nil {
+			fmt.Println("Error saving response body:", err)
+			return
+		}
+	}
+
 	switch outputFormat {
 	case "list":
 		printEndpointDetails(targetEndpoint)
 	case "table":
 		printEndpointTable(targetEndpoint)
-	case "json":
-		printEndpointJSON(targetEndpoint)
 	default:
-		fmt.Println("Invalid output format. Using default list format.")
-		printEndpointDetails(targetEndpoint)
+		if err := output.PrintOne(os.Stdout, outputFormat, targetEndpoint, endpointColumns); err != nil {
+			fmt.Println("Error printing endpoint:", err)
+			os.Exit(1)
+		}
 	}
 }
 
@@ -130,11 +119,69 @@ func printEndpointTable(endpoint map[string]interface{}) {
 	// ... Add other fields as needed ...
 }
 
-func printEndpointJSON(endpoint map[string]interface{}) {
-	jsonData, err := json.MarshalIndent(endpoint, "", "  ")
-	if err != nil {
-		fmt.Println("Error marshaling JSON:", err)
+// fetchEndpoints returns the endpoints of a project, or every endpoint when projectID is empty.
+// The endpoints are also filtered here, for an API ignoring the projectId query not to hand
+// the endpoints of other projects to commands deleting them.
+func fetchEndpoints(token, projectID string) ([]map[string]interface{}, error) {
+	path := "/endpoints"
+	if projectID != "" {
+		path += "?" + url.Values{"projectId": {projectID}}.Encode()
+	}
+
+	var endpoints []map[string]interface{}
+	if err := apiRequest(token, http.MethodGet, path, nil, &endpoints); err != nil {
+		return nil, err
+	}
+	if projectID == "" {
+		return endpoints, nil
+	}
+	inProject := make([]map[string]interface{}, 0, len(endpoints))
+	for _, endpoint := range endpoints {
+		if endpoint["projectId"] == projectID {
+			inProject = append(inProject, endpoint)
+		}
+	}
+	return inProject, nil
+}
+
+// findEndpoint returns the endpoint with the given ID or mock identifier, or nil
+func findEndpoint(endpoints []map[string]interface{}, idOrMockIdentifier string) map[string]interface{} {
+	for _, endpoint := range endpoints {
+		if endpoint["id"] == idOrMockIdentifier || endpoint["mockIdentifier"] == idOrMockIdentifier {
+			return endpoint
+		}
+	}
+	return nil
+}
+
+// setBodyEncoding sets the responseBodyEncoding of an endpoint with a body to text when
+// the API didn't give one, for the body of -o json and -o yaml to be decoded without
+// guessing
+func setBodyEncoding(endpoint map[string]interface{}) {
+	if _, ok := endpoint["responseBody"].(string); !ok {
 		return
 	}
-	fmt.Println(string(jsonData))
+	if encoding, _ := endpoint["responseBodyEncoding"].(string); encoding == "" {
+		endpoint["responseBodyEncoding"] = "text"
+	}
+}
+
+// saveResponseBody writes the stored response body of an endpoint to path
+func saveResponseBody(endpoint map[string]interface{}, path string) error {
+	body, _ := endpoint["responseBody"].(string)
+	content := []byte(body)
+
+	if encoding, _ := endpoint["responseBodyEncoding"].(string); encoding == "base64" {
+		decoded, err := base64.StdEncoding.DecodeString(body)
+		if err != nil {
+			return err
+		}
+		content = decoded
+	}
+
+	if err := os.WriteFile(path, content, 0644); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Saved %d bytes of response body to %s\n", len(content), path)
+	return nil
 }
@@ -0,0 +1,106 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/nicobistolfi/mockthis-cli/internal/utils"
+	"github.com/spf13/cobra"
+)
+
+// InvokeCmd is the command to call a mock endpoint and check its response
+var InvokeCmd = &cobra.Command{
+	Use:   "invoke [id, mockIdentifier, name or endpoint file]",
+	Short: "Call a mock endpoint and check its response against its definition",
+	Long: `Call a mock endpoint and check its response against its definition.
+
+The request uses the method and auth credentials of the endpoint, and a body generated
+from its request schema. The response status, content type, headers and body schema are
+checked, and the command exits with a non-zero status when a check fails.
+
+Endpoint files are called on the local server given by --url, serving the directory given
+by --dir. Names of endpoints created
+from files, in the state of the working directory, call the created endpoint.`,
+	Args: cobra.ExactArgs(1),
+	Run:  invoke,
+}
+
+func init() {
+	InvokeCmd.Flags().String("url", "http://localhost:8080", "URL of the server endpoint files are served on")
+	InvokeCmd.Flags().StringP("dir", "d", ".", "Directory the server serves endpoint files from, which files without a path are named relative to")
+	addClientFlags(InvokeCmd)
+	addVarFlag(InvokeCmd)
+}
+
+func invoke(cmd *cobra.Command, args []string) {
+	vars, err := varFlags(cmd)
+	if err != nil {
+		fmt.Println("Invalid variable:", err)
+		os.Exit(1)
+	}
+
+	dir, _ := cmd.Flags().GetString("dir")
+	c, err := loadContract(args[0], dir, vars)
+	if err != nil {
+		fmt.Println("Error loading endpoint:", err)
+		os.Exit(1)
+	}
+
+	client, err := contractClient(cmd)
+	if err != nil {
+		fmt.Println("Error configuring client:", err)
+		os.Exit(1)
+	}
+
+	baseURL, _ := cmd.Flags().GetString("url")
+	req, resp, checks, err := c.exchange(client, baseURL, nil, true)
+	if err != nil {
+		fmt.Println("Error invoking endpoint:", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("%s %s -> %s\n", req.Method, req.URL, resp.Status)
+	if failed := printChecks(os.Stdout, checks); failed > 0 {
+		fmt.Printf("%d of %d checks failed\n", failed, len(checks))
+		os.Exit(1)
+	}
+	fmt.Printf("All %d checks passed\n", len(checks))
+}
+
+// loadContract reads the contract of an endpoint file, named relative to dir as serve names
+// it, or of a stored endpoint when arg is not a file
+func loadContract(arg, dir string, vars map[string]string) (*contract, error) {
+	if exists, _ := utils.FileExists(arg); exists && isEndpointFile(arg) {
+		return contractFromFile(dir, arg, vars)
+	}
+
+	id, err := resolveEndpointRef(arg)
+	if err != nil {
+		return nil, err
+	}
+	configData := getConfig()
+	endpoints, err := fetchEndpoints(configData.Token, configData.Project)
+	if err != nil {
+		return nil, err
+	}
+	endpoint := findEndpoint(endpoints, id)
+	if endpoint == nil {
+		return nil, fmt.Errorf("endpoint %s not found", arg)
+	}
+	return contractFromAPI(endpoint)
+}
+
+// printChecks writes a PASS or FAIL line for each check and returns how many failed
+func printChecks(w io.Writer, checks []check) int {
+	failed := 0
+	for _, c := range checks {
+		if c.Err != nil {
+			failed++
+			fmt.Fprintf(w, "  FAIL  %s: %v\n", c.Name, c.Err)
+		} else {
+			fmt.Fprintf(w, "  PASS  %s\n", c.Name)
+		}
+	}
+	return failed
+}
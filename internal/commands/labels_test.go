@@ -0,0 +1,104 @@
+package commands
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/nicobistolfi/mockthis-cli/internal/labels"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadFromFileLabels(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "labelled.yml")
+	content := "endpoint:\n  labels:\n    team: payments\n    env: dev\n  response:\n    body: labelled\n"
+	assert.NoError(t, os.WriteFile(path, []byte(content), 0644))
+
+	cmd := &cobra.Command{}
+	cmd.Flags().StringSliceP("label", "l", nil, "")
+	cmd.Flags().String("body", "", "")
+	assert.NoError(t, cmd.ParseFlags([]string{"--label", "env=ci"}))
+
+	assert.NoError(t, loadFromFile(path, cmd))
+
+	// --label takes precedence over the labels of the file
+	pairs, _ := cmd.Flags().GetStringSlice("label")
+	endpointLabels, _, err := labels.Parse(pairs)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"team": "payments", "env": "ci"}, endpointLabels)
+}
+
+func TestSelectEndpoints(t *testing.T) {
+	endpoints := []map[string]interface{}{
+		{"id": "e1", "labels": map[string]interface{}{"team": "payments", "env": "ci"}},
+		{"id": "e2", "labels": map[string]interface{}{"team": "search"}},
+		{"id": "e3"},
+	}
+
+	selected, err := selectEndpoints(endpoints, "team=payments")
+	assert.NoError(t, err)
+	assert.Equal(t, endpoints[:1], selected)
+
+	selected, err = selectEndpoints(endpoints, "!env")
+	assert.NoError(t, err)
+	assert.Equal(t, endpoints[1:], selected)
+
+	selected, err = selectEndpoints(endpoints, "")
+	assert.NoError(t, err)
+	assert.Equal(t, endpoints, selected)
+
+	_, err = selectEndpoints(endpoints, "team in payments")
+	assert.Error(t, err)
+}
+
+func TestLabelChanges(t *testing.T) {
+	var deleted []string
+	var mu sync.Mutex
+	patched := make(map[string]map[string]string)
+	configData := fakeAPI(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method + " " + r.URL.Path {
+		case "GET /endpoints":
+			_ = json.NewEncoder(w).Encode([]map[string]interface{}{
+				{"id": "e1", "labels": map[string]string{"team": "payments", "env": "ci", "old": "x"}},
+				{"id": "e2", "labels": map[string]string{"team": "search", "env": "ci"}},
+				{"id": "e3", "labels": map[string]string{"team": "payments", "env": "prod"}},
+			})
+		case "PATCH /endpoints/e1", "PATCH /endpoints/e2":
+			var body map[string]map[string]string
+			assert.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+			mu.Lock()
+			patched[r.URL.Path] = body["labels"]
+			mu.Unlock()
+		case "DELETE /endpoints/e1", "DELETE /endpoints/e3":
+			deleted = append(deleted, r.URL.Path)
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+
+	endpoints, err := fetchEndpoints(configData.Token, "")
+	assert.NoError(t, err)
+	set, remove, err := labels.Parse([]string{"env=staging", "old-"})
+	assert.NoError(t, err)
+	failures := updateLabels(configData.Token, endpoints, []string{"e1", "e2", "missing"}, set, remove, bulkOptions{Workers: 2})
+	if assert.Len(t, failures, 1) {
+		assert.Equal(t, "missing", failures[0].ID)
+	}
+	assert.Equal(t, map[string]map[string]string{
+		"/endpoints/e1": {"team": "payments", "env": "staging"},
+		"/endpoints/e2": {"team": "search", "env": "staging"},
+	}, patched)
+
+	ids, err := selectedIDs(DeleteEndpointCmd, configData, "team=payments", false)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"e1", "e3"}, ids)
+
+	failures = deleteEndpoints(configData.Token, ids, bulkOptions{Workers: 1})
+	assert.Empty(t, failures)
+	assert.Equal(t, []string{"/endpoints/e1", "/endpoints/e3"}, deleted)
+}
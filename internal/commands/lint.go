@@ -0,0 +1,373 @@
+package commands
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/nicobistolfi/mockthis-cli/internal/server"
+	"github.com/nicobistolfi/mockthis-cli/internal/utils"
+	"github.com/xeipuuv/gojsonschema"
+	"gopkg.in/yaml.v3"
+)
+
+// Lint rules reported by validate
+const (
+	ruleSyntax          = "syntax"
+	ruleExpansion       = "expansion"
+	ruleSchema          = "endpoint-schema"
+	ruleBodyContentType = "body-content-type"
+	ruleBodySchema      = "body-schema"
+	ruleStatusRange     = "status-range"
+	rulePathPattern     = "path-pattern"
+	ruleAuthProperties  = "auth-properties"
+	ruleDuplicateRoute  = "duplicate-route"
+)
+
+// lintRules describes the rules, for reports that list them
+var lintRules = map[string]string{
+	ruleSyntax:          "The file is valid JSON or YAML",
+	ruleExpansion:       "Included files exist and every variable is set",
+	ruleSchema:          "The file is valid against the endpoint file schema",
+	ruleBodyContentType: "The response body is valid for its content type",
+	ruleBodySchema:      "The response body is valid against the response schema",
+	ruleStatusRange:     "The response status is between 100 and 599",
+	rulePathPattern:     "The path is a valid route, with whole segment parameters and wildcards",
+	ruleAuthProperties:  "The auth block has the credentials its type needs",
+	ruleDuplicateRoute:  "No two endpoint files serve the same method and path",
+}
+
+// authCredentials lists the credentials each auth type needs. mtls needs one of its two.
+var authCredentials = map[string][]string{
+	"basic":  {"username", "password"},
+	"apiKey": {"name", "value"},
+	"bearer": {"token"},
+	"jwt":    {"token"},
+	"oauth2": {"accessToken"},
+	"mtls":   {"subject", "san"},
+}
+
+// diagnostic is a problem found in an endpoint file
+type diagnostic struct {
+	File    string `json:"file"`
+	Line    int    `json:"line"`
+	Column  int    `json:"column"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+func (d diagnostic) String() string {
+	return fmt.Sprintf("%s:%d:%d: %s (%s)", d.File, d.Line, d.Column, d.Message, d.Rule)
+}
+
+// diagnosticsError lists diagnostics in an error
+func diagnosticsError(diagnostics []diagnostic) error {
+	var b strings.Builder
+	for _, d := range diagnostics {
+		fmt.Fprintf(&b, "- %s\n", d)
+	}
+	return fmt.Errorf("the data is not valid according to the schema:\n%s", b.String())
+}
+
+// endpointDocument is an endpoint file parsed for linting, keeping the position of every node
+type endpointDocument struct {
+	path string
+	root *yaml.Node
+	data map[string]interface{}
+}
+
+var yamlErrorLine = regexp.MustCompile(`line (\d+)`)
+
+// parseEndpointDocument parses and expands an endpoint file. The data is parsed the way
+// create and serve parse it, and the nodes only give positions.
+func parseEndpointDocument(path, content string, vars map[string]string) (*endpointDocument, []diagnostic) {
+	doc := &endpointDocument{path: path}
+
+	var root yaml.Node
+	if err := yaml.Unmarshal([]byte(content), &root); err != nil {
+		return nil, []diagnostic{syntaxDiagnostic(path, content, err)}
+	}
+
+	changed, err := expandDocument(&root, path, vars)
+	if err != nil {
+		d := diagnostic{File: path, Line: 1, Column: 1, Rule: ruleExpansion, Message: err.Error()}
+		var expandErr *expandError
+		if errors.As(err, &expandErr) {
+			d.File, d.Line, d.Column, d.Message = expandErr.path, expandErr.line, expandErr.column, expandErr.err.Error()
+		}
+		return nil, []diagnostic{d}
+	}
+	if changed {
+		expanded, err := encodeDocument(&root, utils.IsJSON(content))
+		if err != nil {
+			return nil, []diagnostic{{File: path, Line: 1, Column: 1, Rule: ruleExpansion, Message: err.Error()}}
+		}
+		content = string(expanded)
+	}
+
+	if len(root.Content) > 0 {
+		doc.root = root.Content[0]
+	}
+
+	switch {
+	case utils.IsJSON(content):
+		doc.data, err = utils.ParseJSON(content)
+	case utils.IsYAML(content):
+		doc.data, err = utils.ParseYAML(content)
+	default:
+		err = fmt.Errorf("the file is not a JSON or YAML object")
+	}
+	if err != nil {
+		return nil, []diagnostic{syntaxDiagnostic(path, content, err)}
+	}
+	return doc, nil
+}
+
+func syntaxDiagnostic(path, content string, err error) diagnostic {
+	d := diagnostic{File: path, Line: 1, Column: 1, Rule: ruleSyntax, Message: err.Error()}
+
+	if strings.HasPrefix(strings.TrimSpace(content), "{") {
+		var syntaxErr *json.SyntaxError
+		if errors.As(json.Unmarshal([]byte(content), new(interface{})), &syntaxErr) {
+			d.Line, d.Column = offsetPosition(content, int(syntaxErr.Offset)-1)
+			d.Message = syntaxErr.Error()
+			return d
+		}
+	}
+	if match := yamlErrorLine.FindStringSubmatch(err.Error()); match != nil {
+		d.Line, _ = strconv.Atoi(match[1])
+	}
+	return d
+}
+
+func offsetPosition(content string, offset int) (int, int) {
+	if offset < 0 {
+		offset = 0
+	}
+	if offset > len(content) {
+		offset = len(content)
+	}
+	before := content[:offset]
+	line := strings.Count(before, "\n") + 1
+	column := offset - strings.LastIndex(before, "\n")
+	return line, column
+}
+
+// diagnostic returns a diagnostic positioned on the node at path, or on its deepest
+// existing ancestor
+func (doc *endpointDocument) diagnostic(rule, message string, path ...string) diagnostic {
+	line, column := doc.position(path)
+	return diagnostic{File: doc.path, Line: line, Column: column, Rule: rule, Message: message}
+}
+
+func (doc *endpointDocument) position(path []string) (int, int) {
+	node := doc.root
+	if node == nil {
+		return 1, 1
+	}
+	line, column := node.Line, node.Column
+
+	for _, key := range path {
+		for node.Kind == yaml.AliasNode && node.Alias != nil {
+			node = node.Alias
+		}
+
+		var next *yaml.Node
+		switch node.Kind {
+		case yaml.MappingNode:
+			for i := 0; i+1 < len(node.Content); i += 2 {
+				if node.Content[i].Value == key {
+					// Point at the key, where editors expect errors about a field
+					line, column = node.Content[i].Line, node.Content[i].Column
+					next = node.Content[i+1]
+					break
+				}
+			}
+		case yaml.SequenceNode:
+			if index, err := strconv.Atoi(key); err == nil && index >= 0 && index < len(node.Content) {
+				next = node.Content[index]
+				line, column = next.Line, next.Column
+			}
+		}
+		if next == nil {
+			break
+		}
+		node = next
+	}
+	return line, column
+}
+
+// schemaDiagnostics validates the document against the endpoint file schema
+func (doc *endpointDocument) schemaDiagnostics() []diagnostic {
+	result, err := gojsonschema.Validate(gojsonschema.NewStringLoader(ENDPOINT_SCHEMA), gojsonschema.NewGoLoader(doc.data))
+	if err != nil {
+		return []diagnostic{doc.diagnostic(ruleSchema, fmt.Sprintf("error during schema validation: %v", err))}
+	}
+
+	var diagnostics []diagnostic
+	for _, resultErr := range result.Errors() {
+		// A NUL delimiter keeps keys containing dots in one piece
+		path := strings.Split(resultErr.Context().String("\x00"), "\x00")[1:]
+
+		// Errors about a property point at it when it is present
+		if property, ok := resultErr.Details()["property"].(string); ok && resultErr.Type() != "required" {
+			path = append(path, property)
+		}
+
+		message := resultErr.Description()
+		if field := resultErr.Field(); field != "(root)" {
+			message = field + ": " + message
+		}
+		diagnostics = append(diagnostics, doc.diagnostic(ruleSchema, message, path...))
+	}
+	return diagnostics
+}
+
+// lintDiagnostics runs the semantic rules on a document valid against the schema
+func (doc *endpointDocument) lintDiagnostics() []diagnostic {
+	var diagnostics []diagnostic
+	endpoint, _ := doc.data["endpoint"].(map[string]interface{})
+
+	if path, ok := endpoint["path"].(string); ok {
+		if err := server.ValidatePath(path); err != nil {
+			diagnostics = append(diagnostics, doc.diagnostic(rulePathPattern, err.Error(), "endpoint", "path"))
+		}
+	}
+
+	response, _ := endpoint["response"].(map[string]interface{})
+	diagnostics = append(diagnostics, doc.lintResponse(response, nil, "endpoint", "response")...)
+
+	if scenario, ok := endpoint["scenario"].(map[string]interface{}); ok {
+		entries, _ := scenario["responses"].([]interface{})
+		for i, entry := range entries {
+			entryMap, _ := entry.(map[string]interface{})
+			if override, ok := entryMap["response"].(map[string]interface{}); ok {
+				diagnostics = append(diagnostics, doc.lintResponse(override, response, "endpoint", "scenario", "responses", strconv.Itoa(i), "response")...)
+			}
+		}
+	}
+
+	if auth, ok := endpoint["auth"].(map[string]interface{}); ok {
+		authType, _ := auth["type"].(string)
+		required := authCredentials[authType]
+
+		var missing []string
+		for _, key := range required {
+			if credential(auth, key) == "" {
+				missing = append(missing, key)
+			}
+		}
+		if authType == "mtls" && len(missing) < len(required) {
+			missing = nil
+		}
+		if len(missing) > 0 {
+			message := fmt.Sprintf("%s auth needs %s", authType, strings.Join(missing, " and "))
+			if authType == "mtls" {
+				message = "mtls auth needs a subject or a san"
+			}
+			diagnostics = append(diagnostics, doc.diagnostic(ruleAuthProperties, message, "endpoint", "auth"))
+		}
+	}
+
+	return diagnostics
+}
+
+// lintResponse checks a response. Scenario responses inherit the fields of base they do not set.
+func (doc *endpointDocument) lintResponse(response, base map[string]interface{}, path ...string) []diagnostic {
+	var diagnostics []diagnostic
+	at := func(key string) []string {
+		return append(append([]string{}, path...), key)
+	}
+	field := func(key string) interface{} {
+		if value, ok := response[key]; ok {
+			return value
+		}
+		return base[key]
+	}
+
+	if status, ok := response["status"]; ok {
+		code, err := strconv.Atoi(fmt.Sprintf("%v", status))
+		if err == nil && (code < 100 || code > 599) {
+			diagnostics = append(diagnostics, doc.diagnostic(ruleStatusRange, fmt.Sprintf("status %d is not between 100 and 599", code), at("status")...))
+		}
+	}
+
+	body, hasBody := response["body"]
+	if !hasBody {
+		return diagnostics
+	}
+
+	contentType, _ := field("content-type").(string)
+	if contentType == "" {
+		contentType = "application/json"
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType = contentType
+	}
+	isJSON := mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+	isXML := mediaType == "application/xml" || mediaType == "text/xml" || strings.HasSuffix(mediaType, "+xml")
+
+	var parsed interface{}
+	bodyIsJSON := false
+	switch b := body.(type) {
+	case string:
+		bodyIsJSON = json.Unmarshal([]byte(b), &parsed) == nil
+		if isJSON && !bodyIsJSON && strings.TrimSpace(b) != "" {
+			diagnostics = append(diagnostics, doc.diagnostic(ruleBodyContentType, fmt.Sprintf("body is not valid JSON but the content-type is %s", contentType), at("body")...))
+		}
+		if isXML && !wellFormedXML(b) {
+			diagnostics = append(diagnostics, doc.diagnostic(ruleBodyContentType, fmt.Sprintf("body is not well-formed XML but the content-type is %s", contentType), at("body")...))
+		}
+	default:
+		parsed, bodyIsJSON = b, true
+		if !isJSON {
+			diagnostics = append(diagnostics, doc.diagnostic(ruleBodyContentType, fmt.Sprintf("body is an object, which is sent as JSON, but the content-type is %s", contentType), at("body")...))
+		}
+	}
+
+	schema, err := schemaValue(field("schema"))
+	if err == nil && schema != nil && bodyIsJSON {
+		schemaJSON, _ := json.Marshal(schema)
+		result, err := gojsonschema.Validate(gojsonschema.NewBytesLoader(schemaJSON), gojsonschema.NewGoLoader(parsed))
+		if err != nil {
+			diagnostics = append(diagnostics, doc.diagnostic(ruleBodySchema, fmt.Sprintf("invalid response schema: %v", err), at("schema")...))
+		} else {
+			for _, resultErr := range result.Errors() {
+				diagnostics = append(diagnostics, doc.diagnostic(ruleBodySchema, "body does not match the schema: "+resultErr.String(), at("body")...))
+			}
+		}
+	}
+
+	return diagnostics
+}
+
+func wellFormedXML(body string) bool {
+	decoder := xml.NewDecoder(bytes.NewReader([]byte(body)))
+	for {
+		if _, err := decoder.Token(); err != nil {
+			return err == io.EOF
+		}
+	}
+}
+
+// routeKey is the method, path and query an endpoint file is served on
+func routeKey(name string, data map[string]interface{}) (string, bool) {
+	data = deepCopyMap(data)
+	if err := normalizeEndpointFile(data); err != nil {
+		return "", false
+	}
+	endpointData, _ := data["endpoint"].(map[string]interface{})
+	endpoint, err := server.NewEndpoint(name, endpointData)
+	if err != nil {
+		return "", false
+	}
+	return endpoint.Route(), true
+}
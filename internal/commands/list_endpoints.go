@@ -1,15 +1,13 @@
 package commands
 
 import (
-	"encoding/json"
 	"fmt"
-	"io"
-	"net/http"
 	"os"
-	"text/tabwriter"
 	"time"
 
 	"github.com/nicobistolfi/mockthis-cli/internal/config"
+	"github.com/nicobistolfi/mockthis-cli/internal/labels"
+	"github.com/nicobistolfi/mockthis-cli/internal/output"
 	"github.com/spf13/cobra"
 )
 
@@ -20,63 +18,104 @@ var ListEndpointsCmd = &cobra.Command{
 	Run:   listEndpoints,
 }
 
+var listOutputFormat string
+
+func init() {
+	ListEndpointsCmd.Flags().StringVarP(&listOutputFormat, "output", "o", "table", "Output format: "+output.Formats)
+	addProjectFlag(ListEndpointsCmd)
+	ListEndpointsCmd.Flags().BoolP("all-projects", "A", false, "List the endpoints of every project")
+	ListEndpointsCmd.Flags().StringP("selector", "l", "", "Label selector. Eg. 'team=payments,env!=ci' or 'env in (ci,staging)'")
+}
+
 func listEndpoints(cmd *cobra.Command, args []string) {
 	configData, err := config.LoadConfig(config.TokenFile)
 	if err != nil {
 		fmt.Println("You need to login first.")
 		return
 	}
-	token := configData.Token
 
-	req, _ := http.NewRequest("GET", config.BaseURL+"/endpoints", nil)
-	req.Header.Set("Authorization", "Bearer "+token)
+	projectID := ""
+	if allProjects, _ := cmd.Flags().GetBool("all-projects"); !allProjects {
+		if projectID, err = projectFlag(cmd, configData); err != nil {
+			fmt.Println("Error finding project:", err)
+			return
+		}
+	}
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	endpoints, err := fetchEndpoints(configData.Token, projectID)
 	if err != nil {
-		fmt.Println("Error listing endpoints:", err)
+		fmt.Println("Failed to list endpoints:", err)
 		return
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		fmt.Println("Failed to list endpoints. Status:", resp.Status)
-		return
+	selector, _ := cmd.Flags().GetString("selector")
+	if endpoints, err = selectEndpoints(endpoints, selector); err != nil {
+		fmt.Println("Invalid selector:", err)
+		os.Exit(1)
 	}
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		fmt.Println("Error reading response body:", err)
-		return
+	if err := output.Print(os.Stdout, listOutputFormat, endpoints, endpointColumns); err != nil {
+		fmt.Println("Error printing endpoints:", err)
+		os.Exit(1)
+	}
+}
+
+// endpointColumns are the columns of endpoints in the table, wide and csv output formats
+var endpointColumns = []output.Column{
+	{Header: "ID", Field: "id"},
+	{Header: "Method", Field: "method", Format: formatMethod},
+	{Header: "Status", Field: "status"},
+	{Header: "Created At", Field: "createdAt", Format: formatCreatedAt},
+	{Header: "Endpoint URL", Field: "endpointUrl"},
+	{Header: "Path", Field: "path", Wide: true},
+	{Header: "Project", Field: "projectId", Wide: true},
+	{Header: "Labels", Field: "labels", Wide: true, Format: formatLabels},
+	{Header: "Expires At", Field: "expiresAt", Wide: true, Format: formatCreatedAt},
+	{Header: "Mock Identifier", Field: "mockIdentifier", Wide: true},
+	{Header: "Content Type", Field: "responseContentType", Wide: true},
+	{Header: "Auth", Field: "authCredentials", Wide: true, Format: formatAuthType},
+}
+
+// selectEndpoints returns the endpoints whose labels match a label selector
+func selectEndpoints(endpoints []map[string]interface{}, selector string) ([]map[string]interface{}, error) {
+	parsed, err := labels.ParseSelector(selector)
+	if err != nil || len(parsed) == 0 {
+		return endpoints, err
 	}
 
-	var endpoints []struct {
-		ID             string    `json:"id"`
-		HTTPStatus     int       `json:"status"`
-		CreatedAt      time.Time `json:"createdAt"`
-		MockIdentifier string    `json:"mockIdentifier"`
-		EndpointURL    string    `json:"endpointUrl"`
+	selected := make([]map[string]interface{}, 0, len(endpoints))
+	for _, endpoint := range endpoints {
+		if parsed.Matches(labels.FromValue(endpoint["labels"])) {
+			selected = append(selected, endpoint)
+		}
 	}
+	return selected, nil
+}
 
-	err = json.Unmarshal(body, &endpoints)
-	if err != nil {
-		fmt.Println("Error parsing JSON:", err)
-		return
+func formatLabels(value interface{}) string {
+	return labels.Format(labels.FromValue(value))
+}
+
+func formatMethod(value interface{}) string {
+	if method, ok := value.(string); ok && method != "" {
+		return method
 	}
+	return "GET" // Default method
+}
 
-	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
-	fmt.Fprintln(w, "ID\tMethod\tStatus\tCreated At\tEndpoint URL")
-	fmt.Fprintln(w, "--\t------\t------\t----------\t------------")
-
-	for _, e := range endpoints {
-		method := "GET" // Default method
-		fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%s\n",
-			e.ID,
-			method,
-			e.HTTPStatus,
-			e.CreatedAt.Format("2006-01-02 15:04:05"),
-			e.EndpointURL)
+func formatCreatedAt(value interface{}) string {
+	createdAt, _ := value.(string)
+	if t, err := time.Parse(time.RFC3339, createdAt); err == nil {
+		return t.Format("2006-01-02 15:04:05")
 	}
+	return createdAt
+}
 
-	w.Flush()
+func formatAuthType(value interface{}) string {
+	if auth, ok := value.(map[string]interface{}); ok {
+		if authType, ok := auth["type"].(string); ok {
+			return authType
+		}
+	}
+	return ""
 }
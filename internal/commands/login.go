@@ -112,6 +112,10 @@ func saveCredentials(email, token string) {
 		Email: email,
 		Token: token,
 	}
+	// Logging in again to the same account keeps the current project
+	if previous, err := config.LoadConfig(config.TokenFile); err == nil && previous.Email == email {
+		credentials.Project = previous.Project
+	}
 
 	if err := config.SaveConfig(config.TokenFile, credentials); err != nil {
 		fmt.Println("Error saving credentials:", err)
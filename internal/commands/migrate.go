@@ -0,0 +1,142 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/nicobistolfi/mockthis-cli/internal/utils"
+	"github.com/pmezard/go-difflib/difflib"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// MigrateCmd is the command to rewrite endpoint files in the current format version
+var MigrateCmd = &cobra.Command{
+	Use:   "migrate [files or directories...] [--dry-run]",
+	Short: "Rewrite endpoint files in the current format version",
+	Long: `Rewrite v1 endpoint files as ` + currentAPIVersion + ` files, in place.
+
+The method moves from the response to the endpoint, auth credentials nested under
+properties move next to the auth type, and an apiVersion key is added. Comments and the
+order of keys are kept. A diff of each change is printed, use --dry-run to only print it.`,
+	Run: migrate,
+}
+
+func init() {
+	MigrateCmd.Flags().Bool("dry-run", false, "Print the changes without writing them")
+	addVarFlag(MigrateCmd)
+}
+
+func migrate(cmd *cobra.Command, args []string) {
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+	vars, err := varFlags(cmd)
+	if err != nil {
+		fmt.Println("Invalid variable:", err)
+		os.Exit(1)
+	}
+	if len(args) == 0 {
+		args = []string{"."}
+	}
+
+	var paths []string
+	for _, arg := range args {
+		info, err := os.Stat(arg)
+		if err != nil {
+			fmt.Println("Error reading endpoint files:", err)
+			os.Exit(1)
+		}
+		if !info.IsDir() {
+			paths = append(paths, arg)
+			continue
+		}
+		found, err := findEndpointFiles(arg)
+		if err != nil {
+			fmt.Println("Error reading endpoint files:", err)
+			os.Exit(1)
+		}
+		paths = append(paths, found...)
+	}
+
+	migrated, failed := 0, 0
+	for _, path := range paths {
+		changed, diff, err := migrateFile(path, dryRun, vars)
+		if err != nil {
+			fmt.Printf("Error migrating %s: %v\n", path, err)
+			failed++
+			continue
+		}
+		if changed {
+			fmt.Print(diff)
+			migrated++
+		}
+	}
+
+	verb := "Migrated"
+	if dryRun {
+		verb = "Would migrate"
+	}
+	fmt.Printf("%s %d of %d endpoint files to %s\n", verb, migrated, len(paths), currentAPIVersion)
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
+
+// migrateFile rewrites an endpoint file in the current version unless dryRun is set, and
+// returns a unified diff of the change. vars are the --var variables, to validate the change.
+func migrateFile(path string, dryRun bool, vars map[string]string) (bool, string, error) {
+	content, err := utils.LoadFile(path)
+	if err != nil {
+		return false, "", err
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(content), &doc); err != nil {
+		return false, "", err
+	}
+	changed, err := migrateDocument(&doc)
+	if err != nil || !changed {
+		return false, "", err
+	}
+
+	isJSON := utils.IsJSON(content)
+	migrated, err := encodeDocument(&doc, isJSON)
+	if err != nil {
+		return false, "", err
+	}
+
+	// Never write a file create and serve would reject
+	parsed, diagnostics := parseEndpointDocument(path, string(migrated), vars)
+	if parsed != nil {
+		diagnostics = parsed.schemaDiagnostics()
+	}
+	if len(diagnostics) > 0 {
+		return false, "", fmt.Errorf("the migrated file is not valid, fix the file and retry: %v", diagnosticsError(diagnostics))
+	}
+
+	diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(content),
+		B:        difflib.SplitLines(string(migrated)),
+		FromFile: filepath.ToSlash(path),
+		ToFile:   filepath.ToSlash(path) + " (" + currentAPIVersion + ")",
+		Context:  3,
+	})
+	if err != nil {
+		return false, "", err
+	}
+	if !strings.HasSuffix(diff, "\n") {
+		diff += "\n"
+	}
+
+	if !dryRun {
+		info, err := os.Stat(path)
+		if err != nil {
+			return false, "", err
+		}
+		if err := os.WriteFile(path, migrated, info.Mode().Perm()); err != nil {
+			return false, "", err
+		}
+	}
+	return true, diff, nil
+}
@@ -0,0 +1,257 @@
+package commands
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/nicobistolfi/mockthis-cli/internal/config"
+	"github.com/nicobistolfi/mockthis-cli/internal/output"
+	"github.com/spf13/cobra"
+)
+
+// ProjectCmd is the command to manage the projects endpoints are grouped in
+var ProjectCmd = &cobra.Command{
+	Use:   "project",
+	Short: "Manage the projects endpoints are grouped in",
+	Long: `Manage the projects endpoints are grouped in.
+
+The endpoints of a project share its base URL. create, list and get work on the current
+project, set with 'mockthis project use', or on the project given with --project.`,
+}
+
+var projectCreateCmd = &cobra.Command{
+	Use:   "create [name]",
+	Short: "Create a project",
+	Args:  cobra.ExactArgs(1),
+	Run:   createProject,
+}
+
+var projectListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List projects, marking the current one",
+	Args:  cobra.NoArgs,
+	Run:   listProjects,
+}
+
+var projectUseCmd = &cobra.Command{
+	Use:   "use [name or id]",
+	Short: "Set the current project",
+	Args:  cobra.MaximumNArgs(1),
+	Run:   useProject,
+}
+
+var projectDeleteCmd = &cobra.Command{
+	Use:   "delete [name or id]",
+	Short: "Delete a project and its endpoints",
+	Args:  cobra.ExactArgs(1),
+	Run:   deleteProject,
+}
+
+func init() {
+	projectCreateCmd.Flags().String("description", "", "Description of the project")
+	projectCreateCmd.Flags().Bool("use", false, "Set the project as the current project")
+	projectListCmd.Flags().StringP("output", "o", "table", "Output format: "+output.Formats)
+	projectUseCmd.Flags().Bool("none", false, "Unset the current project, to work on endpoints outside projects")
+	projectDeleteCmd.Flags().BoolP("yes", "y", false, "Delete without asking for confirmation")
+	addBulkFlags(projectDeleteCmd)
+
+	ProjectCmd.AddCommand(projectCreateCmd)
+	ProjectCmd.AddCommand(projectListCmd)
+	ProjectCmd.AddCommand(projectUseCmd)
+	ProjectCmd.AddCommand(projectDeleteCmd)
+}
+
+// projectColumns are the columns of projects in the table, wide and csv output formats.
+// current is the ID of the current project.
+func projectColumns(current string) []output.Column {
+	return []output.Column{
+		{Header: "Current", Field: "id", Format: func(value interface{}) string {
+			if value == current && current != "" {
+				return "*"
+			}
+			return ""
+		}},
+		{Header: "ID", Field: "id"},
+		{Header: "Name", Field: "name"},
+		{Header: "Base URL", Field: "baseUrl"},
+		{Header: "Created At", Field: "createdAt", Format: formatCreatedAt},
+		{Header: "Description", Field: "description", Wide: true},
+	}
+}
+
+func createProject(cmd *cobra.Command, args []string) {
+	description, _ := cmd.Flags().GetString("description")
+	use, _ := cmd.Flags().GetBool("use")
+	configData := getConfig()
+
+	payload := map[string]interface{}{"name": args[0]}
+	if description != "" {
+		payload["description"] = description
+	}
+
+	var project map[string]interface{}
+	if err := apiRequest(configData.Token, http.MethodPost, "/projects", payload, &project); err != nil {
+		fmt.Println("Error creating project:", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Project %s created\nID: %v\nBase URL: %v\n", args[0], project["id"], project["baseUrl"])
+
+	if use {
+		id, _ := project["id"].(string)
+		setCurrentProject(configData, id, args[0])
+	}
+}
+
+func listProjects(cmd *cobra.Command, args []string) {
+	format, _ := cmd.Flags().GetString("output")
+	configData := getConfig()
+
+	projects, err := fetchProjects(configData.Token)
+	if err != nil {
+		fmt.Println("Error fetching projects:", err)
+		os.Exit(1)
+	}
+	if err := output.Print(os.Stdout, format, projects, projectColumns(configData.Project)); err != nil {
+		fmt.Println("Error printing projects:", err)
+		os.Exit(1)
+	}
+}
+
+func useProject(cmd *cobra.Command, args []string) {
+	none, _ := cmd.Flags().GetBool("none")
+	if none == (len(args) == 1) {
+		fmt.Println("Give a project name or ID, or --none.")
+		os.Exit(1)
+	}
+	configData := getConfig()
+
+	if none {
+		setCurrentProject(configData, "", "")
+		return
+	}
+
+	project, err := findProject(configData.Token, args[0])
+	if err != nil {
+		fmt.Println("Error finding project:", err)
+		os.Exit(1)
+	}
+	id, _ := project["id"].(string)
+	name, _ := project["name"].(string)
+	setCurrentProject(configData, id, name)
+}
+
+func setCurrentProject(configData *config.Data, id, name string) {
+	configData.Project = id
+	if err := config.SaveConfig(config.TokenFile, configData); err != nil {
+		fmt.Println("Error saving config:", err)
+		os.Exit(1)
+	}
+	if id == "" {
+		fmt.Println("No current project")
+		return
+	}
+	fmt.Printf("Current project is %s (%s)\n", name, id)
+}
+
+func deleteProject(cmd *cobra.Command, args []string) {
+	yes, _ := cmd.Flags().GetBool("yes")
+	options, err := bulkFlags(cmd)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	configData := getConfig()
+
+	project, err := findProject(configData.Token, args[0])
+	if err != nil {
+		fmt.Println("Error finding project:", err)
+		os.Exit(1)
+	}
+	id, _ := project["id"].(string)
+	name, _ := project["name"].(string)
+
+	endpoints, err := fetchEndpoints(configData.Token, id)
+	if err != nil {
+		fmt.Println("Error fetching endpoints:", err)
+		os.Exit(1)
+	}
+
+	if !yes && !confirm(fmt.Sprintf("Delete project %s and its %d endpoints?", name, len(endpoints))) {
+		fmt.Println("Aborted")
+		return
+	}
+
+	ids := make([]string, 0, len(endpoints))
+	for _, endpoint := range endpoints {
+		endpointID, _ := endpoint["id"].(string)
+		ids = append(ids, endpointID)
+	}
+	if failures := deleteEndpoints(configData.Token, ids, options); len(failures) > 0 {
+		printBulkSummary(os.Stdout, "Deleted", len(ids), failures)
+		fmt.Println("The project was not deleted")
+		os.Exit(1)
+	}
+	if err := apiRequest(configData.Token, http.MethodDelete, "/projects/"+url.PathEscape(id), nil, nil); err != nil {
+		fmt.Println("Error deleting project:", err)
+		os.Exit(1)
+	}
+
+	if configData.Project == id {
+		configData.Project = ""
+		if err := config.SaveConfig(config.TokenFile, configData); err != nil {
+			fmt.Println("Error saving config:", err)
+			os.Exit(1)
+		}
+	}
+	fmt.Printf("Project %s and its %d endpoints deleted\n", name, len(endpoints))
+}
+
+func fetchProjects(token string) ([]map[string]interface{}, error) {
+	var projects []map[string]interface{}
+	if err := apiRequest(token, http.MethodGet, "/projects", nil, &projects); err != nil {
+		return nil, err
+	}
+	return projects, nil
+}
+
+// findProject returns the project with the given ID or name
+func findProject(token, idOrName string) (map[string]interface{}, error) {
+	projects, err := fetchProjects(token)
+	if err != nil {
+		return nil, err
+	}
+	for _, project := range projects {
+		if project["id"] == idOrName || project["name"] == idOrName {
+			return project, nil
+		}
+	}
+	return nil, fmt.Errorf("project %s not found", idOrName)
+}
+
+// addProjectFlag adds the --project flag of the commands working on the endpoints of a project
+func addProjectFlag(cmd *cobra.Command) {
+	cmd.Flags().String("project", "", "Project name or ID, defaults to the current project")
+}
+
+// projectFlag returns the ID of the project given with --project, or of the current project
+func projectFlag(cmd *cobra.Command, configData *config.Data) (string, error) {
+	idOrName, _ := cmd.Flags().GetString("project")
+	if idOrName == "" {
+		return configData.Project, nil
+	}
+	project, err := findProject(configData.Token, idOrName)
+	if err != nil {
+		return "", err
+	}
+	id, _ := project["id"].(string)
+	return id, nil
+}
+
+// confirm asks a yes or no question, no being the default
+func confirm(question string) bool {
+	answer := promptForInput(question + " [y/N] ")
+	return strings.EqualFold(answer, "y") || strings.EqualFold(answer, "yes")
+}
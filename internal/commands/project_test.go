@@ -0,0 +1,88 @@
+package commands
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/nicobistolfi/mockthis-cli/internal/config"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProjects(t *testing.T) {
+	var deleted []string
+	fakeAPI(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method + " " + r.URL.RequestURI() {
+		case "GET /projects":
+			_, _ = w.Write([]byte(`[{"id": "p1", "name": "payments"}, {"id": "p2", "name": "search"}]`))
+		case "GET /endpoints?projectId=p1":
+			// Endpoints of other projects are never deleted, even if the API gives them
+			_, _ = w.Write([]byte(`[{"id": "e1", "projectId": "p1"}, {"id": "e2", "projectId": "p1"}, {"id": "e3", "projectId": "p2"}, {"id": "e4"}]`))
+		case "DELETE /endpoints/e1", "DELETE /endpoints/e2", "DELETE /projects/p1":
+			deleted = append(deleted, r.URL.Path)
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+
+	// The current project is the default of --project
+	useProject(projectUseCmd, []string{"payments"})
+	configData, err := config.LoadConfig(config.TokenFile)
+	assert.NoError(t, err)
+	assert.Equal(t, "p1", configData.Project)
+
+	cmd := &cobra.Command{}
+	addProjectFlag(cmd)
+	projectID, err := projectFlag(cmd, configData)
+	assert.NoError(t, err)
+	assert.Equal(t, "p1", projectID)
+
+	assert.NoError(t, cmd.Flags().Set("project", "search"))
+	projectID, err = projectFlag(cmd, configData)
+	assert.NoError(t, err)
+	assert.Equal(t, "p2", projectID)
+
+	assert.NoError(t, cmd.Flags().Set("project", "unknown"))
+	_, err = projectFlag(cmd, configData)
+	assert.EqualError(t, err, "project unknown not found")
+
+	// Deleting the current project deletes its endpoints and unsets it
+	assert.NoError(t, projectDeleteCmd.Flags().Set("yes", "true"))
+	deleteProject(projectDeleteCmd, []string{"p1"})
+	assert.Equal(t, []string{"/endpoints/e1", "/endpoints/e2", "/projects/p1"}, deleted)
+	configData, err = config.LoadConfig(config.TokenFile)
+	assert.NoError(t, err)
+	assert.Empty(t, configData.Project)
+}
+
+func TestSelectAllNeedsProject(t *testing.T) {
+	configData := fakeAPI(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method + " " + r.URL.RequestURI() {
+		case "GET /endpoints":
+			_, _ = w.Write([]byte(`[{"id": "e1", "projectId": "p1"}, {"id": "e2", "projectId": "p2"}]`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	newCmd := func() *cobra.Command {
+		cmd := &cobra.Command{}
+		cmd.Flags().BoolP("all-projects", "A", false, "")
+		addProjectFlag(cmd)
+		return cmd
+	}
+
+	// Without a current project, --all would select every endpoint of the account
+	_, err := selectedIDs(newCmd(), configData, "", true)
+	assert.EqualError(t, err, "there is no current project: give one with --project, or use --all-projects to select the endpoints of every project")
+
+	cmd := newCmd()
+	assert.NoError(t, cmd.Flags().Set("all-projects", "true"))
+	ids, err := selectedIDs(cmd, configData, "", true)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"e1", "e2"}, ids)
+
+	assert.NoError(t, cmd.Flags().Set("project", "p1"))
+	_, err = selectedIDs(cmd, configData, "", true)
+	assert.EqualError(t, err, "--project and --all-projects can't be used together")
+}
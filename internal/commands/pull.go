@@ -0,0 +1,183 @@
+package commands
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/nicobistolfi/mockthis-cli/internal/config"
+	"github.com/spf13/cobra"
+)
+
+// PullCmd is the command to write remote endpoints as local endpoint files
+var PullCmd = &cobra.Command{
+	Use:   "pull [id...]",
+	Short: "Write remote endpoints as local endpoint files",
+	Long: `Write endpoints as endpoint files, to bring endpoints created with flags under version
+control. Without IDs every endpoint of the project is pulled, or those matching -l.
+
+Files are named after the method and path of the endpoint, or its ID when it has no path.
+Files that changed since they were pulled are only overwritten with --force. Pulled files are
+recorded in the state of the directory, for other commands to refer to them by file or name.`,
+	Example: `  mockthis pull --dir mocks/
+  mockthis pull 1a2b3c 4d5e6f --dir mocks/
+  mockthis pull -l team=payments --dir mocks/payments`,
+	Run: pull,
+}
+
+func init() {
+	PullCmd.Flags().String("dir", ".", "Directory to write the endpoint files to")
+	PullCmd.Flags().StringP("selector", "l", "", "Label selector of the endpoints to pull. Eg. 'team=payments'")
+	PullCmd.Flags().Bool("force", false, "Overwrite files that differ from the pulled endpoint")
+	addProjectFlag(PullCmd)
+	PullCmd.Flags().BoolP("all-projects", "A", false, "Pull the endpoints of every project")
+}
+
+func pull(cmd *cobra.Command, args []string) {
+	configData, err := config.LoadConfig(config.TokenFile)
+	if err != nil {
+		fmt.Println("You need to login first.")
+		return
+	}
+	dir, _ := cmd.Flags().GetString("dir")
+	selector, _ := cmd.Flags().GetString("selector")
+	force, _ := cmd.Flags().GetBool("force")
+
+	endpoints, err := pulledEndpoints(cmd, configData, args, selector)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	if len(endpoints) == 0 {
+		fmt.Println("No endpoints to pull")
+		return
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		fmt.Println("Error creating directory:", err)
+		os.Exit(1)
+	}
+
+	var failures []bulkFailure
+	names := make(map[string]bool)
+	for _, endpoint := range endpoints {
+		id := stringField(endpoint, "id")
+		name := pulledFileName(endpoint, names)
+		filePath := filepath.Join(dir, name)
+
+		status, err := pullEndpoint(endpoint, filePath, force)
+		if err == nil {
+			err = recordEndpoint(filePath, id, stringField(endpoint, "endpointUrl"))
+		}
+		if err != nil {
+			failures = append(failures, bulkFailure{ID: id, Err: err})
+			continue
+		}
+		fmt.Printf("%s %s -> %s\n", status, id, filePath)
+	}
+
+	printBulkSummary(os.Stdout, "Pulled", len(endpoints), failures)
+	if len(failures) > 0 {
+		os.Exit(1)
+	}
+}
+
+// pulledEndpoints returns the endpoints given by ID or mock identifier, or else those of
+// the project matching selector
+func pulledEndpoints(cmd *cobra.Command, configData *config.Data, ids []string, selector string) ([]map[string]interface{}, error) {
+	projectID := ""
+	allProjects, _ := cmd.Flags().GetBool("all-projects")
+	if len(ids) == 0 && !allProjects {
+		var err error
+		if projectID, err = projectFlag(cmd, configData); err != nil {
+			return nil, fmt.Errorf("error finding project: %w", err)
+		}
+	}
+
+	endpoints, err := fetchEndpoints(configData.Token, projectID)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching endpoints: %w", err)
+	}
+	if len(ids) == 0 {
+		selected, err := selectEndpoints(endpoints, selector)
+		if err != nil {
+			return nil, fmt.Errorf("invalid selector: %w", err)
+		}
+		return selected, nil
+	}
+
+	selected := make([]map[string]interface{}, 0, len(ids))
+	for _, id := range uniqueIDs(ids) {
+		endpoint := findEndpoint(endpoints, id)
+		if endpoint == nil {
+			return nil, fmt.Errorf("endpoint %s not found", id)
+		}
+		selected = append(selected, endpoint)
+	}
+	return selected, nil
+}
+
+// pullEndpoint writes the endpoint file of an endpoint, reporting whether it was written,
+// updated or already up to date
+func pullEndpoint(endpoint map[string]interface{}, filePath string, force bool) (string, error) {
+	content, err := endpointFileContent(endpoint)
+	if err != nil {
+		return "", err
+	}
+	content = append([]byte(pulledFileHeader(endpoint)), content...)
+	return writeEndpointFile(filePath, content, "Pulled", force)
+}
+
+// writeEndpointFile writes the content of an endpoint file, reporting whether it was written,
+// with status, updated or already up to date. Files that differ are only overwritten with force.
+func writeEndpointFile(filePath string, content []byte, status string, force bool) (string, error) {
+	if existing, err := os.ReadFile(filePath); err == nil {
+		if bytes.Equal(existing, content) {
+			return "Unchanged", nil
+		}
+		if !force {
+			return "", fmt.Errorf("%s differs from the endpoint, use --force to overwrite it", filePath)
+		}
+		status = "Updated"
+	}
+
+	if err := os.WriteFile(filePath, content, 0644); err != nil {
+		return "", err
+	}
+	return status, nil
+}
+
+// pulledFileHeader is the comment recording the endpoint a file was pulled from
+func pulledFileHeader(endpoint map[string]interface{}) string {
+	header := "# Pulled from endpoint " + stringField(endpoint, "id")
+	if url := stringField(endpoint, "endpointUrl"); url != "" {
+		header += " (" + url + ")"
+	}
+	return header + "\n"
+}
+
+// pulledFileName names the file of an endpoint after its method and path, or its ID, adding
+// the ID to names already taken by another endpoint of the same pull
+func pulledFileName(endpoint map[string]interface{}, taken map[string]bool) string {
+	id := unsafeFileChars.ReplaceAllString(stringField(endpoint, "id"), "-")
+
+	name := id
+	if endpointPath := stringField(endpoint, "path"); endpointPath != "" {
+		method := strings.ToLower(stringField(endpoint, "method"))
+		if method == "" {
+			method = "get"
+		}
+		route := strings.Trim(unsafeFileChars.ReplaceAllString(endpointPath, "-"), "-")
+		if route == "" {
+			route = "root"
+		}
+		name = method + "-" + route
+	}
+
+	if taken[name+".yml"] {
+		name += "-" + id
+	}
+	taken[name+".yml"] = true
+	return name + ".yml"
+}
@@ -0,0 +1,77 @@
+package commands
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPullEndpoint(t *testing.T) {
+	dir := t.TempDir()
+	endpoint := apiEndpoint()
+	filePath := filepath.Join(dir, pulledFileName(endpoint, map[string]bool{}))
+	assert.Equal(t, "post-charges-id.yml", filepath.Base(filePath))
+
+	status, err := pullEndpoint(endpoint, filePath, false)
+	assert.NoError(t, err)
+	assert.Equal(t, "Pulled", status)
+
+	content, err := os.ReadFile(filePath)
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(string(content), "# Pulled from endpoint e1 (https://mock.example.com/e1)\n"))
+
+	// The pulled file creates the same endpoint
+	payload, err := endpointPayloadFromFile(filePath, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "POST", payload["method"])
+	assert.Equal(t, "/charges/{id}", payload["path"])
+	assert.Equal(t, `{"id": "ch_1"}`, payload["responseBody"])
+
+	status, err = pullEndpoint(endpoint, filePath, false)
+	assert.NoError(t, err)
+	assert.Equal(t, "Unchanged", status)
+
+	endpoint["status"] = float64(500)
+	_, err = pullEndpoint(endpoint, filePath, false)
+	assert.Error(t, err)
+	status, err = pullEndpoint(endpoint, filePath, true)
+	assert.NoError(t, err)
+	assert.Equal(t, "Updated", status)
+}
+
+func TestPulledFileName(t *testing.T) {
+	taken := map[string]bool{}
+	assert.Equal(t, "get-users-id.yml", pulledFileName(map[string]interface{}{"id": "e1", "path": "/users/{id}"}, taken))
+	assert.Equal(t, "get-users-id-e2.yml", pulledFileName(map[string]interface{}{"id": "e2", "method": "GET", "path": "/users/{id}"}, taken))
+	assert.Equal(t, "e3.yml", pulledFileName(map[string]interface{}{"id": "e3"}, taken))
+	assert.Equal(t, "delete-root.yml", pulledFileName(map[string]interface{}{"id": "e4", "method": "DELETE", "path": "/"}, taken))
+}
+
+func TestPulledFileReadsBack(t *testing.T) {
+	// Text of the endpoint looking like variables is neither filled in nor missing
+	t.Setenv("X", "leaked")
+	dir := t.TempDir()
+	endpoint := apiEndpoint()
+	endpoint["responseBody"] = `{"greeting": "Hello ${X}", "other": "${MISSING}"}`
+	endpoint["httpHeaders"] = `{"X-Template": "${X}"}`
+	filePath := filepath.Join(dir, pulledFileName(endpoint, map[string]bool{}))
+	_, err := pullEndpoint(endpoint, filePath, false)
+	assert.NoError(t, err)
+
+	// create --file sends the stored endpoint
+	payload, err := endpointPayloadFromFile(filePath, map[string]string{"X": "from-var"})
+	assert.NoError(t, err)
+	assert.Equal(t, endpoint["responseBody"], payload["responseBody"])
+	assert.JSONEq(t, `{"X-Template": "${X}"}`, payload["httpHeaders"].(string))
+
+	// serve and invoke read the same endpoint
+	served, err := loadEndpoint(dir, filePath, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, endpoint["responseBody"], string(served.Response.Body))
+	assert.Equal(t, "${X}", served.Response.Headers["X-Template"])
+	_, err = contractFromFile(dir, filePath, nil)
+	assert.NoError(t, err)
+}
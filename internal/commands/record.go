@@ -0,0 +1,341 @@
+package commands
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/nicobistolfi/mockthis-cli/internal/utils"
+	"github.com/spf13/cobra"
+)
+
+// RecordCmd is the command to record a real API into endpoint files
+var RecordCmd = &cobra.Command{
+	Use:   "record --target <url> [--dir <path>] [--host <host>] [--port <port>] [--redact-header <name>] [--redact-query <name>] [--ignore-query]",
+	Short: "Proxy a real API and record its responses as endpoint files",
+	Args:  cobra.NoArgs,
+	Run:   record,
+}
+
+// defaultRedactedHeaders are the headers whose values are never written to disk. Requests
+// only have their Content-Type recorded, so these are redacted in responses.
+var defaultRedactedHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie"}
+
+// defaultRedactedQuery are the query parameters whose values are never written to disk or
+// printed. They are recorded as *, which matches any value.
+var defaultRedactedQuery = []string{"access_token", "api_key", "apikey", "key", "password", "secret", "signature", "token"}
+
+// skippedHeaders are response headers that describe the transfer rather than the response
+var skippedHeaders = map[string]bool{
+	"Connection":        true,
+	"Content-Encoding":  true,
+	"Content-Length":    true,
+	"Content-Type":      true,
+	"Date":              true,
+	"Keep-Alive":        true,
+	"Proxy-Connection":  true,
+	"Trailer":           true,
+	"Transfer-Encoding": true,
+	"Upgrade":           true,
+}
+
+func init() {
+	RecordCmd.Flags().String("target", "", "URL of the API to record")
+	RecordCmd.Flags().StringP("dir", "d", ".", "Directory to write endpoint files to")
+	RecordCmd.Flags().String("host", "localhost", "Host to listen on")
+	RecordCmd.Flags().IntP("port", "p", 8080, "Port to listen on")
+	RecordCmd.Flags().StringSlice("redact-header", defaultRedactedHeaders, "Headers whose values are redacted in recorded files")
+	RecordCmd.Flags().StringSlice("redact-query", defaultRedactedQuery, "Query parameters whose values are redacted in recorded files and logs")
+	RecordCmd.Flags().Bool("ignore-query", false, "Deduplicate requests by method and path only")
+	_ = RecordCmd.MarkFlagRequired("target")
+}
+
+type recorder struct {
+	dir         string
+	redact      map[string]bool
+	redactQuery map[string]bool
+	ignoreQuery bool
+
+	mu   sync.Mutex
+	seen map[string]bool
+	// names maps the recorded file names to the key of the request recorded in them
+	names map[string]string
+}
+
+func record(cmd *cobra.Command, args []string) {
+	target, _ := cmd.Flags().GetString("target")
+	dir, _ := cmd.Flags().GetString("dir")
+	host, _ := cmd.Flags().GetString("host")
+	port, _ := cmd.Flags().GetInt("port")
+	redactHeaders, _ := cmd.Flags().GetStringSlice("redact-header")
+	redactQuery, _ := cmd.Flags().GetStringSlice("redact-query")
+	ignoreQuery, _ := cmd.Flags().GetBool("ignore-query")
+
+	targetURL, err := parseServerURL(target)
+	if err != nil {
+		fmt.Println("Invalid target URL:", err)
+		os.Exit(1)
+	}
+
+	rec := newRecorder(dir, redactHeaders, redactQuery, ignoreQuery)
+	recorded, err := rec.loadRecorded()
+	if err != nil {
+		fmt.Println("Error reading recorded files:", err)
+		os.Exit(1)
+	}
+	if recorded > 0 {
+		fmt.Printf("Skipping the %d requests already recorded in %s\n", recorded, dir)
+	}
+	addr := net.JoinHostPort(host, strconv.Itoa(port))
+	fmt.Printf("Recording %s on http://%s into %s\n", targetURL, addr, dir)
+
+	if err := http.ListenAndServe(addr, rec.proxy(targetURL)); err != nil {
+		fmt.Println("Error recording endpoints:", err)
+		os.Exit(1)
+	}
+}
+
+func newRecorder(dir string, redactHeaders, redactQuery []string, ignoreQuery bool) *recorder {
+	redact := make(map[string]bool, len(redactHeaders))
+	for _, header := range redactHeaders {
+		redact[http.CanonicalHeaderKey(strings.TrimSpace(header))] = true
+	}
+	redactParams := make(map[string]bool, len(redactQuery))
+	for _, param := range redactQuery {
+		redactParams[strings.ToLower(strings.TrimSpace(param))] = true
+	}
+	return &recorder{
+		dir:         dir,
+		redact:      redact,
+		redactQuery: redactParams,
+		ignoreQuery: ignoreQuery,
+		seen:        make(map[string]bool),
+		names:       make(map[string]string),
+	}
+}
+
+// loadRecorded marks the requests recorded in the files of the directory as seen, so that a
+// new recording skips them and doesn't overwrite their files, and returns their number
+func (r *recorder) loadRecorded() (int, error) {
+	entries, err := os.ReadDir(r.dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	recorded := 0
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".yml" {
+			continue
+		}
+		// A file that isn't a recording still takes its name
+		r.names[entry.Name()] = ""
+		content, err := utils.LoadFile(filepath.Join(r.dir, entry.Name()))
+		if err != nil {
+			continue
+		}
+		data, err := utils.ParseYAML(content)
+		if err != nil {
+			continue
+		}
+		endpoint, _ := data["endpoint"].(map[string]interface{})
+		method, _ := endpoint["method"].(string)
+		path, _ := endpoint["path"].(string)
+		if method == "" || path == "" {
+			continue
+		}
+		key := method + " " + path
+		r.names[entry.Name()] = key
+		if !r.seen[key] {
+			r.seen[key] = true
+			recorded++
+		}
+	}
+	return recorded, nil
+}
+
+func (r *recorder) proxy(target *url.URL) *httputil.ReverseProxy {
+	proxy := httputil.NewSingleHostReverseProxy(target)
+
+	director := proxy.Director
+	proxy.Director = func(req *http.Request) {
+		director(req)
+		req.Host = target.Host
+		// Ask for an uncompressed body so it can be written to the endpoint file as is
+		req.Header.Del("Accept-Encoding")
+	}
+
+	proxy.ModifyResponse = func(resp *http.Response) error {
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return err
+		}
+		resp.Body = io.NopCloser(bytes.NewReader(body))
+
+		if err := r.save(resp.Request, resp, body); err != nil {
+			fmt.Printf("Not recording %s %s: %v\n", resp.Request.Method, r.recordedPath(resp.Request), err)
+		}
+		return nil
+	}
+
+	return proxy
+}
+
+// save writes the request/response pair as an endpoint file unless it was already recorded
+func (r *recorder) save(req *http.Request, resp *http.Response, body []byte) error {
+	path := r.recordedPath(req)
+	key := req.Method + " " + path
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.seen[key] {
+		return nil
+	}
+
+	fileData, err := r.endpointFile(req, resp, body)
+	if err != nil {
+		return err
+	}
+	if err := utils.ValidateAgainstSchema(fileData, ENDPOINT_SCHEMA); err != nil {
+		return err
+	}
+
+	content, err := utils.ToYAML(fileData)
+	if err != nil {
+		return err
+	}
+
+	filePath := filepath.Join(r.dir, r.fileName(req, key))
+	if err := utils.WriteFile(filePath, content); err != nil {
+		return err
+	}
+
+	r.seen[key] = true
+	fmt.Printf("Recorded %s %s -> %s\n", req.Method, path, filePath)
+	return nil
+}
+
+func (r *recorder) endpointFile(req *http.Request, resp *http.Response, body []byte) (map[string]interface{}, error) {
+	response := map[string]interface{}{
+		"status": resp.StatusCode,
+	}
+	if utf8.Valid(body) {
+		response["body"] = string(body)
+	} else {
+		response["body_base64"] = base64.StdEncoding.EncodeToString(body)
+	}
+
+	if mediaType, params, err := mime.ParseMediaType(resp.Header.Get("Content-Type")); err == nil {
+		response["content-type"] = mediaType
+		if charset, ok := params["charset"]; ok {
+			response["charset"] = strings.ToUpper(charset)
+		}
+	}
+
+	headers := make(map[string]interface{})
+	for name, values := range resp.Header {
+		if skippedHeaders[name] {
+			continue
+		}
+		if r.redact[name] {
+			headers[name] = "REDACTED"
+			continue
+		}
+		headers[name] = strings.Join(values, ", ")
+	}
+	if len(headers) > 0 {
+		response["headers"] = headers
+	}
+
+	endpoint := map[string]interface{}{
+		"method":   req.Method,
+		"path":     r.recordedPath(req),
+		"response": response,
+	}
+
+	if mediaType, _, err := mime.ParseMediaType(req.Header.Get("Content-Type")); err == nil {
+		endpoint["request"] = map[string]interface{}{"content-type": mediaType}
+	}
+
+	return map[string]interface{}{"apiVersion": currentAPIVersion, "endpoint": endpoint}, nil
+}
+
+// recordedPath is the path of a request as recorded, with its query unless --ignore-query
+// is given, and the values of redacted query parameters replaced with *
+func (r *recorder) recordedPath(req *http.Request) string {
+	if r.ignoreQuery || req.URL.RawQuery == "" {
+		return req.URL.Path
+	}
+
+	query := req.URL.Query()
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var params []string
+	for _, key := range keys {
+		if r.redactQuery[strings.ToLower(key)] {
+			params = append(params, url.QueryEscape(key)+"=*")
+			continue
+		}
+		for _, value := range query[key] {
+			params = append(params, url.QueryEscape(key)+"="+url.QueryEscape(value))
+		}
+	}
+	return req.URL.Path + "?" + strings.Join(params, "&")
+}
+
+var unsafeFileChars = regexp.MustCompile(`[^a-zA-Z0-9._-]+`)
+
+// fileName names the recording of the request with key after its method and path, plus a
+// hash of the query. A name already taken by another request gets a hash of the key.
+func (r *recorder) fileName(req *http.Request, key string) string {
+	name := recordedFileName(req, r.recordedPath(req))
+	if taken, ok := r.names[name]; ok && taken != key {
+		sum := sha1.Sum([]byte(key))
+		name = strings.TrimSuffix(name, ".yml") + "-" + hex.EncodeToString(sum[:])[:8] + ".yml"
+	}
+	r.names[name] = key
+	return name
+}
+
+// recordedFileName names a recording after its method and path, plus a hash of the query
+// of its recorded path
+func recordedFileName(req *http.Request, recordedPath string) string {
+	name := strings.Trim(unsafeFileChars.ReplaceAllString(req.URL.Path, "-"), "-")
+	if name == "" {
+		name = "root"
+	}
+	name = strings.ToLower(req.Method) + "-" + name
+
+	if _, query, ok := strings.Cut(recordedPath, "?"); ok {
+		sum := sha1.Sum([]byte(query))
+		name += "-" + hex.EncodeToString(sum[:])[:8]
+	}
+
+	return name + ".yml"
+}
@@ -0,0 +1,151 @@
+package commands
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecorder(t *testing.T) {
+	calls := 0
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.Header().Set("Set-Cookie", "session=secret")
+		w.Header().Set("X-Request-Path", r.URL.Path)
+		w.WriteHeader(http.StatusCreated)
+		_, _ = io.WriteString(w, `{"ok": true}`)
+	}))
+	defer target.Close()
+
+	dir := t.TempDir()
+	targetURL, _ := url.Parse(target.URL)
+	proxy := httptest.NewServer(newRecorder(dir, defaultRedactedHeaders, defaultRedactedQuery, false).proxy(targetURL))
+	defer proxy.Close()
+
+	for _, path := range []string{"/users/42", "/users/42", "/users/42?page=2"} {
+		resp, err := http.Post(proxy.URL+path, "application/json", nil)
+		assert.NoError(t, err)
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusCreated, resp.StatusCode)
+		assert.Equal(t, `{"ok": true}`, string(body))
+	}
+	assert.Equal(t, 3, calls)
+
+	files, err := os.ReadDir(dir)
+	assert.NoError(t, err)
+	assert.Len(t, files, 2)
+
+	endpoint, err := readEndpointFile(filepath.Join(dir, "post-users-42.yml"), nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "/users/42", endpoint["path"])
+
+	response := endpoint["response"].(map[string]interface{})
+	assert.Equal(t, "POST", response["method"])
+	assert.Equal(t, "201", response["status"])
+	assert.Equal(t, "application/json", response["content-type"])
+	assert.Equal(t, "UTF-8", response["charset"])
+	assert.Equal(t, `{"ok": true}`, response["body"])
+	assert.Equal(t, map[string]interface{}{
+		"Set-Cookie":     "REDACTED",
+		"X-Request-Path": "/users/42",
+	}, response["headers"])
+}
+
+func TestRecorderSkipsRecordedFiles(t *testing.T) {
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "second run")
+	}))
+	defer target.Close()
+
+	dir := t.TempDir()
+	recorded := filepath.Join(dir, "get-users.yml")
+	assert.NoError(t, os.WriteFile(recorded, []byte("apiVersion: v2\nendpoint:\n  method: GET\n  path: /users\n  response:\n    body: first run\n"), 0644))
+	// A hand-written file with the name of a recording
+	other := filepath.Join(dir, "get-orders.yml")
+	assert.NoError(t, os.WriteFile(other, []byte("notes"), 0644))
+
+	rec := newRecorder(dir, nil, nil, false)
+	count, err := rec.loadRecorded()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	targetURL, _ := url.Parse(target.URL)
+	proxy := httptest.NewServer(rec.proxy(targetURL))
+	defer proxy.Close()
+	for _, path := range []string{"/users", "/orders"} {
+		resp, err := http.Get(proxy.URL + path)
+		assert.NoError(t, err)
+		resp.Body.Close()
+	}
+
+	// Neither file is overwritten, and the new request gets another name
+	content, err := os.ReadFile(recorded)
+	assert.NoError(t, err)
+	assert.Contains(t, string(content), "first run")
+	content, err = os.ReadFile(other)
+	assert.NoError(t, err)
+	assert.Equal(t, "notes", string(content))
+	files, err := filepath.Glob(filepath.Join(dir, "get-orders-*.yml"))
+	assert.NoError(t, err)
+	assert.Len(t, files, 1)
+
+	// A directory not created yet has no recordings
+	count, err = newRecorder(filepath.Join(dir, "missing"), nil, nil, false).loadRecorded()
+	assert.NoError(t, err)
+	assert.Zero(t, count)
+}
+
+func TestRecorderRedactsRequests(t *testing.T) {
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "ok")
+	}))
+	defer target.Close()
+
+	dir := t.TempDir()
+	targetURL, _ := url.Parse(target.URL)
+	proxy := httptest.NewServer(newRecorder(dir, defaultRedactedHeaders, defaultRedactedQuery, false).proxy(targetURL))
+	defer proxy.Close()
+
+	for _, token := range []string{"secret-1", "secret-2"} {
+		req, _ := http.NewRequest(http.MethodGet, proxy.URL+"/users?page=2&Token="+token, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("Cookie", "session="+token)
+		resp, err := http.DefaultClient.Do(req)
+		assert.NoError(t, err)
+		resp.Body.Close()
+	}
+
+	// Requests differing by a redacted value are one recording
+	files, err := os.ReadDir(dir)
+	assert.NoError(t, err)
+	assert.Len(t, files, 1)
+
+	content, err := os.ReadFile(filepath.Join(dir, files[0].Name()))
+	assert.NoError(t, err)
+	assert.NotContains(t, string(content), "secret")
+
+	endpoint, err := readEndpointFile(filepath.Join(dir, files[0].Name()), nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "/users?Token=*&page=2", endpoint["path"])
+}
+
+func TestRecorderFileNames(t *testing.T) {
+	rec := newRecorder(t.TempDir(), nil, nil, true)
+	for _, test := range []struct{ path, name string }{
+		{"/users/42", "get-users-42.yml"},
+		{"/users/42?page=2", "get-users-42.yml"},
+		{"/users-42", "get-users-42-a09c458b.yml"},
+		{"/users/42", "get-users-42.yml"},
+	} {
+		req := httptest.NewRequest(http.MethodGet, test.path, nil)
+		assert.Equal(t, test.name, rec.fileName(req, req.Method+" "+rec.recordedPath(req)), test.path)
+	}
+}
@@ -0,0 +1,155 @@
+package commands
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"unicode"
+
+	"github.com/nicobistolfi/mockthis-cli/internal/utils"
+)
+
+// endpointFileFromAPI maps an endpoint returned by the API back to a v2 endpoint file, the
+// reverse of the payload `create --file` sends
+func endpointFileFromAPI(endpoint map[string]interface{}) (map[string]interface{}, error) {
+	file := make(map[string]interface{})
+
+	if method := stringField(endpoint, "method"); method != "" {
+		file["method"] = strings.ToUpper(method)
+	}
+	for _, field := range []string{"path", "expiresAt"} {
+		if value := stringField(endpoint, field); value != "" {
+			file[field] = value
+		}
+	}
+	if endpointLabels, ok := endpoint["labels"].(map[string]interface{}); ok && len(endpointLabels) > 0 {
+		file["labels"] = endpointLabels
+	}
+
+	response := make(map[string]interface{})
+	switch status := endpoint["status"].(type) {
+	case float64:
+		response["status"] = int(status)
+	case string:
+		response["status"] = status
+	}
+	if contentType := stringField(endpoint, "responseContentType"); contentType != "" {
+		response["content-type"] = contentType
+	}
+	if charset := stringField(endpoint, "charset"); charset != "" {
+		response["charset"] = charset
+	}
+	if headers := headersValue(endpoint["httpHeaders"]); len(headers) > 0 {
+		response["headers"] = headers
+	}
+	if body, ok := endpoint["responseBody"].(string); ok {
+		if stringField(endpoint, "responseBodyEncoding") == "base64" {
+			response["body_base64"] = body
+		} else {
+			response["body"] = body
+		}
+	}
+	schema, err := schemaValue(endpoint["responseBodySchema"])
+	if err != nil {
+		return nil, fmt.Errorf("response schema: %v", err)
+	}
+	if schema != nil {
+		response["schema"] = schema
+	}
+	file["response"] = response
+
+	request := make(map[string]interface{})
+	if contentType := stringField(endpoint, "requestContentType"); contentType != "" {
+		request["content-type"] = contentType
+	}
+	if schema, err = schemaValue(endpoint["requestBodySchema"]); err != nil {
+		return nil, fmt.Errorf("request schema: %v", err)
+	}
+	if schema != nil {
+		request["schema"] = schema
+	}
+	if len(request) > 0 {
+		file["request"] = request
+	}
+
+	if credentials, ok := endpoint["authCredentials"].(map[string]interface{}); ok {
+		if auth := authValue(credentials); auth != nil {
+			file["auth"] = auth
+		}
+	}
+	if cors, ok := endpoint["cors"].(map[string]interface{}); ok && len(cors) > 0 {
+		policy := make(map[string]interface{}, len(cors))
+		for key, value := range cors {
+			policy[toKebabCase(key)] = integerValue(value)
+		}
+		file["cors"] = policy
+	}
+
+	data := map[string]interface{}{"apiVersion": currentAPIVersion, "endpoint": file}
+	if err := utils.ValidateAgainstSchema(data, ENDPOINT_SCHEMA); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// headersValue reads response headers stored as an object, or as a string of JSON or
+// comma-separated pairs
+func headersValue(value interface{}) map[string]interface{} {
+	headers := make(map[string]interface{})
+	switch h := value.(type) {
+	case map[string]interface{}:
+		for key, v := range h {
+			headers[key] = fmt.Sprintf("%v", v)
+		}
+	case string:
+		for key, v := range parseHeaderList(h) {
+			headers[key] = v
+		}
+	}
+	return headers
+}
+
+// authValue flattens stored credentials into the auth block of a v2 file, dropping the
+// properties the endpoint was created without
+func authValue(credentials map[string]interface{}) map[string]interface{} {
+	authType := stringField(credentials, "type")
+	if authType == "" {
+		return nil
+	}
+
+	auth := map[string]interface{}{"type": authType}
+	properties, _ := credentials["properties"].(map[string]interface{})
+	for _, values := range []map[string]interface{}{credentials, properties} {
+		for key, value := range values {
+			if key == "type" || key == "properties" || value == nil || value == "" {
+				continue
+			}
+			auth[key] = integerValue(value)
+		}
+	}
+	return auth
+}
+
+// integerValue turns the whole numbers decoded from JSON back into integers, which is what
+// the file format expects of them
+func integerValue(value interface{}) interface{} {
+	if n, ok := value.(float64); ok && n == math.Trunc(n) {
+		return int(n)
+	}
+	return value
+}
+
+// toKebabCase reverses toCamelCase
+func toKebabCase(s string) string {
+	var b strings.Builder
+	for i, r := range s {
+		if unicode.IsUpper(r) {
+			if i > 0 {
+				b.WriteByte('-')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -0,0 +1,227 @@
+package commands
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"sync"
+
+	"github.com/nicobistolfi/mockthis-cli/internal/config"
+	"github.com/nicobistolfi/mockthis-cli/internal/output"
+	"github.com/spf13/cobra"
+)
+
+// RestoreCmd is the command to recreate the endpoints of a backup
+var RestoreCmd = &cobra.Command{
+	Use:   "restore <archive>",
+	Short: "Recreate the endpoints of a backup",
+	Long: `Recreate the endpoints of an archive written by 'mockthis backup', in the account of the
+current profile. Endpoints go to the project of the same name as their project in the backup,
+which is created when missing, or all to the project given with --project.
+
+Restored endpoints get new IDs and mock URLs: the report maps each ID of the backup to the ID
+of the endpoint created from it.`,
+	Example: `  mockthis restore backup.tar.gz
+  mockthis restore backup.tar.gz --profile production -o json > remap.json`,
+	Args: cobra.ExactArgs(1),
+	Run:  restore,
+}
+
+var restoreColumns = []output.Column{
+	{Header: "Backup ID", Field: "backupId"},
+	{Header: "ID", Field: "id"},
+	{Header: "Mock URL", Field: "mockUrl"},
+	{Header: "Project", Field: "project"},
+}
+
+func init() {
+	RestoreCmd.Flags().String("project", "", "Project name or ID to restore every endpoint to")
+	RestoreCmd.Flags().StringP("output", "o", "table", "Output format of the ID remapping: "+output.Formats)
+	addBulkFlags(RestoreCmd)
+}
+
+func restore(cmd *cobra.Command, args []string) {
+	configData, err := config.LoadConfig(config.TokenFile)
+	if err != nil {
+		fmt.Println("You need to login first.")
+		return
+	}
+	format, _ := cmd.Flags().GetString("output")
+	options, err := bulkFlags(cmd)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	manifest, files, err := readBackup(args[0])
+	if err != nil {
+		fmt.Println("Error reading backup:", err)
+		os.Exit(1)
+	}
+
+	payloads, expired, failures := restorePayloads(manifest, files)
+
+	projects, err := restoreProjects(cmd, configData, manifest)
+	if err != nil {
+		fmt.Println("Error preparing projects:", err)
+		os.Exit(1)
+	}
+
+	var (
+		mu      sync.Mutex
+		created = make(map[string]map[string]interface{})
+		ids     []string
+	)
+	for _, endpoint := range manifest.Endpoints {
+		if payload, ok := payloads[endpoint.ID]; ok {
+			if project := projects[endpoint.ProjectID]; project.ID != "" {
+				payload["projectId"] = project.ID
+			}
+			ids = append(ids, endpoint.ID)
+		}
+	}
+
+	failures = append(failures, runBulk(ids, options, progressWriter(), func(id string) error {
+		var response struct {
+			ID      string `json:"id"`
+			MockURL string `json:"mockUrl"`
+		}
+		if err := apiRequest(configData.Token, http.MethodPost, "/endpoints", payloads[id], &response); err != nil {
+			return err
+		}
+		mu.Lock()
+		created[id] = map[string]interface{}{"id": response.ID, "mockUrl": response.MockURL}
+		mu.Unlock()
+		return nil
+	})...)
+
+	var rows []map[string]interface{}
+	for _, endpoint := range manifest.Endpoints {
+		row, ok := created[endpoint.ID]
+		if !ok {
+			continue
+		}
+		row["backupId"] = endpoint.ID
+		row["project"] = projects[endpoint.ProjectID].Name
+		rows = append(rows, row)
+	}
+	if err := output.Print(os.Stdout, format, rows, restoreColumns); err != nil {
+		fmt.Println("Error printing the restored endpoints:", err)
+		os.Exit(1)
+	}
+
+	// The summary goes to stderr when stdout is meant for other programs
+	summary := os.Stdout
+	if format != "table" && format != "wide" {
+		summary = os.Stderr
+	}
+	for _, endpoint := range manifest.Endpoints {
+		if expiresAt, ok := expired[endpoint.ID]; ok && created[endpoint.ID] != nil {
+			fmt.Fprintf(summary, "Endpoint %s expired at %s, restored without an expiry\n", endpoint.ID, expiresAt)
+		}
+	}
+	printBulkSummary(summary, "Restored", len(manifest.Endpoints), failures)
+	if len(failures) > 0 {
+		os.Exit(1)
+	}
+}
+
+// restorePayloads builds the create payload of every endpoint of the backup by ID, reading
+// their files as `create --file` does. Endpoints whose payload can't be built are failures.
+// Expiries that have passed are dropped, and returned by ID in expired.
+func restorePayloads(manifest *backupManifest, files map[string][]byte) (payloads map[string]map[string]interface{}, expired map[string]string, failures []bulkFailure) {
+	payloads = make(map[string]map[string]interface{})
+	expired = make(map[string]string)
+
+	dir, err := os.MkdirTemp("", "mockthis-restore-")
+	if err != nil {
+		for _, endpoint := range manifest.Endpoints {
+			failures = append(failures, bulkFailure{ID: endpoint.ID, Err: fmt.Errorf("creating a temporary directory: %w", err)})
+		}
+		return payloads, expired, failures
+	}
+	defer os.RemoveAll(dir)
+
+	for i, endpoint := range manifest.Endpoints {
+		filePath := filepath.Join(dir, fmt.Sprintf("%d-%s", i, path.Base(endpoint.File)))
+		if err := os.WriteFile(filePath, files[path.Clean(endpoint.File)], 0644); err != nil {
+			failures = append(failures, bulkFailure{ID: endpoint.ID, Err: err})
+			continue
+		}
+		cmd, err := endpointFileCommand(filePath, nil)
+		if err != nil {
+			failures = append(failures, bulkFailure{ID: endpoint.ID, Err: err})
+			continue
+		}
+		if expiresAt := dropPastExpiry(cmd); expiresAt != "" {
+			expired[endpoint.ID] = expiresAt
+		}
+		payload, err := endpointPayload(cmd)
+		if err != nil {
+			failures = append(failures, bulkFailure{ID: endpoint.ID, Err: err})
+			continue
+		}
+		payloads[endpoint.ID] = payload
+	}
+	return payloads, expired, failures
+}
+
+// restoreProjects maps the project IDs of the backup to the projects endpoints are restored
+// to: the --project project, or the project of the same name, created when missing
+func restoreProjects(cmd *cobra.Command, configData *config.Data, manifest *backupManifest) (map[string]backupProject, error) {
+	projects := make(map[string]backupProject)
+
+	if idOrName, _ := cmd.Flags().GetString("project"); idOrName != "" {
+		project, err := findProject(configData.Token, idOrName)
+		if err != nil {
+			return nil, err
+		}
+		target := backupProject{ID: stringField(project, "id"), Name: stringField(project, "name")}
+		for _, endpoint := range manifest.Endpoints {
+			projects[endpoint.ProjectID] = target
+		}
+		return projects, nil
+	}
+
+	used := make(map[string]bool)
+	for _, endpoint := range manifest.Endpoints {
+		if endpoint.ProjectID != "" {
+			used[endpoint.ProjectID] = true
+		}
+	}
+	if len(used) == 0 {
+		return projects, nil
+	}
+
+	existing, err := fetchProjects(configData.Token)
+	if err != nil {
+		return nil, err
+	}
+	byName := make(map[string]string)
+	for _, project := range existing {
+		byName[stringField(project, "name")] = stringField(project, "id")
+	}
+
+	for _, project := range manifest.Projects {
+		if !used[project.ID] {
+			continue
+		}
+		id, ok := byName[project.Name]
+		if !ok {
+			payload := map[string]interface{}{"name": project.Name}
+			if project.Description != "" {
+				payload["description"] = project.Description
+			}
+			var created map[string]interface{}
+			if err := apiRequest(configData.Token, http.MethodPost, "/projects", payload, &created); err != nil {
+				return nil, fmt.Errorf("error creating project %s: %v", project.Name, err)
+			}
+			id = stringField(created, "id")
+			fmt.Printf("Created project %s (%s)\n", project.Name, id)
+		}
+		projects[project.ID] = backupProject{ID: id, Name: project.Name}
+	}
+	return projects, nil
+}
@@ -0,0 +1,95 @@
+package commands
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/nicobistolfi/mockthis-cli/internal/server"
+	"github.com/spf13/cobra"
+)
+
+// ScenarioCmd is the command to drive the scenarios of a local mock server
+var ScenarioCmd = &cobra.Command{
+	Use:   "scenario",
+	Short: "Set or reset the state of scenarios on a local mock server",
+}
+
+var scenarioSetCmd = &cobra.Command{
+	Use:   "set [name] [state]",
+	Short: "Move a scenario to the given state",
+	Args:  cobra.ExactArgs(2),
+	Run:   setScenario,
+}
+
+var scenarioResetCmd = &cobra.Command{
+	Use:   "reset [name]",
+	Short: "Reset a scenario, or all scenarios, to the Started state",
+	Args:  cobra.MaximumNArgs(1),
+	Run:   resetScenario,
+}
+
+func init() {
+	ScenarioCmd.PersistentFlags().String("server", "http://localhost:8080", "URL of the local mock server")
+
+	ScenarioCmd.AddCommand(scenarioSetCmd)
+	ScenarioCmd.AddCommand(scenarioResetCmd)
+}
+
+func setScenario(cmd *cobra.Command, args []string) {
+	name, state := args[0], args[1]
+	jsonData, _ := json.Marshal(map[string]string{"state": state})
+
+	path := "/scenarios/" + url.PathEscape(name) + "/state"
+	if err := callAdminAPI(cmd, http.MethodPut, path, jsonData); err != nil {
+		fmt.Println("Error setting scenario state:", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Scenario %s is now in state %s\n", name, state)
+}
+
+func resetScenario(cmd *cobra.Command, args []string) {
+	path := "/scenarios/reset"
+	message := "All scenarios reset to " + server.StartedState
+	if len(args) > 0 {
+		path = "/scenarios/" + url.PathEscape(args[0]) + "/reset"
+		message = fmt.Sprintf("Scenario %s reset to %s", args[0], server.StartedState)
+	}
+
+	if err := callAdminAPI(cmd, http.MethodPost, path, nil); err != nil {
+		fmt.Println("Error resetting scenario:", err)
+		os.Exit(1)
+	}
+
+	fmt.Println(message)
+}
+
+// callAdminAPI sends a request to the admin API of the server given by the --server flag
+func callAdminAPI(cmd *cobra.Command, method, path string, body []byte) error {
+	serverURL, _ := cmd.Flags().GetString("server")
+
+	req, err := http.NewRequest(method, strings.TrimSuffix(serverURL, "/")+server.AdminPrefix+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		return fmt.Errorf("admin API returned %s", resp.Status)
+	}
+	return nil
+}
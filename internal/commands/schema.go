@@ -0,0 +1,124 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+)
+
+// schemaFileName is the name the endpoint file schema is installed under in .vscode
+const schemaFileName = "mockthis-endpoint.schema.json"
+
+// includeTag declares the !include tag of endpoint files to the YAML extension
+const includeTag = "!include scalar"
+
+// SchemaCmd is the command to print or install the endpoint file JSON schema
+var SchemaCmd = &cobra.Command{
+	Use:   "schema",
+	Short: "Print the endpoint file JSON schema or install it in an editor",
+}
+
+var schemaPrintCmd = &cobra.Command{
+	Use:   "print",
+	Short: "Print the endpoint file JSON schema",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		fmt.Print(ENDPOINT_SCHEMA)
+	},
+}
+
+var schemaInstallCmd = &cobra.Command{
+	Use:   "install --vscode",
+	Short: "Configure an editor to validate and autocomplete endpoint files",
+	Long: `Configure an editor to validate and autocomplete endpoint files.
+
+With --vscode, the schema is written to .vscode/` + schemaFileName + ` and the
+yaml.schemas setting of .vscode/settings.json maps it to the endpoint files, *.mock.yml
+and *.mock.yaml by default, and yaml.customTags declares the !include tag. The YAML
+extension by Red Hat reads the settings.`,
+	Args: cobra.NoArgs,
+	Run:  installSchema,
+}
+
+func init() {
+	schemaInstallCmd.Flags().Bool("vscode", false, "Configure Visual Studio Code")
+	schemaInstallCmd.Flags().StringP("dir", "d", ".", "Workspace directory")
+	schemaInstallCmd.Flags().StringSlice("glob", []string{"*.mock.yml", "*.mock.yaml"}, "Glob patterns of the endpoint files")
+
+	SchemaCmd.AddCommand(schemaPrintCmd)
+	SchemaCmd.AddCommand(schemaInstallCmd)
+}
+
+func installSchema(cmd *cobra.Command, args []string) {
+	vscode, _ := cmd.Flags().GetBool("vscode")
+	dir, _ := cmd.Flags().GetString("dir")
+	globs, _ := cmd.Flags().GetStringSlice("glob")
+
+	if !vscode {
+		fmt.Println("Choose the editor to configure. Supported editors: --vscode")
+		os.Exit(1)
+	}
+
+	settingsPath, err := installVSCodeSchema(dir, globs)
+	if err != nil {
+		fmt.Println("Error installing schema:", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Endpoint file schema configured in %s for %v\n", settingsPath, globs)
+}
+
+// installVSCodeSchema writes the schema to the .vscode directory of the workspace and maps
+// it to globs in the yaml.schemas setting, keeping the other settings
+func installVSCodeSchema(dir string, globs []string) (string, error) {
+	vscodeDir := filepath.Join(dir, ".vscode")
+	if err := os.MkdirAll(vscodeDir, 0755); err != nil {
+		return "", err
+	}
+
+	if err := os.WriteFile(filepath.Join(vscodeDir, schemaFileName), []byte(ENDPOINT_SCHEMA), 0644); err != nil {
+		return "", err
+	}
+
+	settingsPath := filepath.Join(vscodeDir, "settings.json")
+	settings := make(map[string]interface{})
+	content, err := os.ReadFile(settingsPath)
+	switch {
+	case err == nil:
+		if err := json.Unmarshal(content, &settings); err != nil {
+			return "", fmt.Errorf("%s is not plain JSON, add \"yaml.schemas\": {\"./.vscode/%s\": %q} to it by hand: %v", settingsPath, schemaFileName, globs, err)
+		}
+	case !os.IsNotExist(err):
+		return "", err
+	}
+
+	schemas, _ := settings["yaml.schemas"].(map[string]interface{})
+	if schemas == nil {
+		schemas = make(map[string]interface{})
+	}
+	schemas["./.vscode/"+schemaFileName] = globs
+	settings["yaml.schemas"] = schemas
+
+	// Without it the extension reports the !include tag of endpoint files as unknown
+	customTags, _ := settings["yaml.customTags"].([]interface{})
+	if !containsValue(customTags, includeTag) {
+		settings["yaml.customTags"] = append(customTags, includeTag)
+	}
+
+	encoded, err := json.MarshalIndent(settings, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return settingsPath, os.WriteFile(settingsPath, append(encoded, '\n'), 0644)
+}
+
+func containsValue(values []interface{}, value interface{}) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,71 @@
+package commands
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEndpointSchemaDescriptions(t *testing.T) {
+	var schema struct {
+		Schema      string `json:"$schema"`
+		Definitions map[string]struct {
+			Description string                            `json:"description"`
+			Properties  map[string]map[string]interface{} `json:"properties"`
+		} `json:"definitions"`
+	}
+	assert.NoError(t, json.Unmarshal([]byte(ENDPOINT_SCHEMA), &schema))
+	assert.Equal(t, "http://json-schema.org/draft-07/schema#", schema.Schema)
+
+	for name, definition := range schema.Definitions {
+		assert.NotEmpty(t, definition.Description, name)
+		for property, value := range definition.Properties {
+			assert.NotEmpty(t, value["description"], name+"."+property)
+		}
+	}
+}
+
+func TestInstallVSCodeSchema(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, os.MkdirAll(filepath.Join(dir, ".vscode"), 0755))
+	existing := `{"editor.tabSize": 2, "yaml.schemas": {"https://json.schemastore.org/github-workflow.json": ".github/workflows/*.yml"}}`
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, ".vscode", "settings.json"), []byte(existing), 0644))
+
+	settingsPath, err := installVSCodeSchema(dir, []string{"*.mock.yml"})
+	assert.NoError(t, err)
+
+	content, err := os.ReadFile(settingsPath)
+	assert.NoError(t, err)
+	var settings map[string]interface{}
+	assert.NoError(t, json.Unmarshal(content, &settings))
+	assert.Equal(t, float64(2), settings["editor.tabSize"])
+	assert.Equal(t, map[string]interface{}{
+		"https://json.schemastore.org/github-workflow.json": ".github/workflows/*.yml",
+		"./.vscode/" + schemaFileName:                       []interface{}{"*.mock.yml"},
+	}, settings["yaml.schemas"])
+	assert.Equal(t, []interface{}{includeTag}, settings["yaml.customTags"])
+
+	// Installing again doesn't declare the tag twice
+	_, err = installVSCodeSchema(dir, []string{"*.mock.yml"})
+	assert.NoError(t, err)
+	content, _ = os.ReadFile(settingsPath)
+	assert.NoError(t, json.Unmarshal(content, &settings))
+	assert.Equal(t, []interface{}{includeTag}, settings["yaml.customTags"])
+
+	schema, err := os.ReadFile(filepath.Join(dir, ".vscode", schemaFileName))
+	assert.NoError(t, err)
+	assert.Equal(t, ENDPOINT_SCHEMA, string(schema))
+
+	// Settings with comments are left alone
+	assert.NoError(t, os.WriteFile(settingsPath, []byte("// comment\n{}"), 0644))
+	_, err = installVSCodeSchema(dir, []string{"*.mock.yml"})
+	assert.Error(t, err)
+}
+
+func TestEndpointName(t *testing.T) {
+	assert.Equal(t, "users/list", endpointName("mocks", filepath.Join("mocks", "users", "list.yml")))
+	assert.Equal(t, "users", endpointName("mocks", filepath.Join("mocks", "users.mock.yaml")))
+}
@@ -0,0 +1,327 @@
+package commands
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/fs"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/nicobistolfi/mockthis-cli/internal/server"
+	"github.com/nicobistolfi/mockthis-cli/internal/utils"
+	"github.com/spf13/cobra"
+)
+
+// ServeCmd is the command to serve endpoint files from a local mock server
+var ServeCmd = &cobra.Command{
+	Use:   "serve [--dir <path>] [--host <host>] [--port <port>] [--upstream <url>] [--watch=false] [--tls] [--cert <path> --key <path>] [--client-ca <path>] [--cors-origins <origins>]",
+	Short: "Serve endpoint files from a local mock server",
+	Args:  cobra.NoArgs,
+	Run:   serve,
+}
+
+func init() {
+	ServeCmd.Flags().StringP("dir", "d", ".", "Directory containing JSON or YAML endpoint files")
+	ServeCmd.Flags().String("host", "localhost", "Host to listen on")
+	ServeCmd.Flags().IntP("port", "p", 8080, "Port to listen on")
+	ServeCmd.Flags().String("upstream", "", "URL to proxy requests that match no endpoint to")
+	ServeCmd.Flags().Bool("watch", true, "Reload endpoint files when they change")
+	addVarFlag(ServeCmd)
+
+	// TLS
+	ServeCmd.Flags().Bool("tls", false, "Serve HTTPS with a certificate signed by a generated CA")
+	ServeCmd.Flags().String("ca-file", "mockthis-ca.pem", "Path to write the generated CA certificate to")
+	ServeCmd.Flags().String("cert", "", "Path to a PEM certificate to serve HTTPS with")
+	ServeCmd.Flags().String("key", "", "Path to the PEM private key of --cert")
+	ServeCmd.Flags().String("client-ca", "", "Path to PEM CA certificates clients must present a certificate signed by (mutual TLS)")
+
+	// CORS policy for endpoints without a cors block
+	ServeCmd.Flags().StringSlice("cors-origins", nil, "Allowed CORS origins, '*' for any")
+	ServeCmd.Flags().StringSlice("cors-methods", nil, "Allowed CORS methods, defaults to the requested method")
+	ServeCmd.Flags().StringSlice("cors-headers", nil, "Allowed CORS request headers, defaults to the requested headers")
+	ServeCmd.Flags().Bool("cors-credentials", false, "Allow credentialed CORS requests")
+	ServeCmd.Flags().Int("cors-max-age", 0, "Seconds browsers may cache preflight responses")
+}
+
+func serve(cmd *cobra.Command, args []string) {
+	dir, _ := cmd.Flags().GetString("dir")
+	host, _ := cmd.Flags().GetString("host")
+	port, _ := cmd.Flags().GetInt("port")
+	upstream, _ := cmd.Flags().GetString("upstream")
+	watch, _ := cmd.Flags().GetBool("watch")
+
+	vars, err := varFlags(cmd)
+	if err != nil {
+		fmt.Println("Invalid variable:", err)
+		os.Exit(1)
+	}
+
+	endpoints, err := loadEndpoints(dir, vars)
+	if err != nil {
+		fmt.Println("Error loading endpoints:", err)
+		os.Exit(1)
+	}
+
+	srv, err := server.New(endpoints)
+	if err != nil {
+		fmt.Println("Error starting server:", err)
+		os.Exit(1)
+	}
+
+	srv.SetValidator(func(data map[string]interface{}) error {
+		if err := utils.ValidateAgainstSchema(data, ENDPOINT_SCHEMA); err != nil {
+			return err
+		}
+		return normalizeEndpointFile(data)
+	})
+
+	if upstream != "" {
+		upstreamURL, err := parseServerURL(upstream)
+		if err != nil {
+			fmt.Println("Invalid upstream URL:", err)
+			os.Exit(1)
+		}
+		srv.SetUpstream(upstreamURL)
+	}
+
+	if cors := serverCORS(cmd); cors != nil {
+		srv.SetCORS(cors)
+	}
+
+	if watch {
+		err := srv.Watch(dir, func() ([]*server.Endpoint, error) { return loadEndpoints(dir, vars) }, nil)
+		if err != nil {
+			fmt.Println("Error watching endpoint files:", err)
+			os.Exit(1)
+		}
+	}
+
+	tlsConfig, err := serverTLSConfig(cmd, host)
+	if err != nil {
+		fmt.Println("Error configuring TLS:", err)
+		os.Exit(1)
+	}
+	// Without --client-ca no client certificate is asked for, and mtls endpoints would
+	// answer every request with 401. Reloaded endpoints and those added through the admin
+	// API are checked too.
+	if clientCA, _ := cmd.Flags().GetString("client-ca"); clientCA == "" {
+		if err := checkNoMTLS(endpoints); err != nil {
+			fmt.Println("Error configuring TLS:", err)
+			os.Exit(1)
+		}
+		srv.SetEndpointCheck(checkNoMTLS)
+	}
+	scheme := "http"
+	if tlsConfig != nil {
+		scheme = "https"
+	}
+
+	addr := net.JoinHostPort(host, strconv.Itoa(port))
+	fmt.Printf("Serving %d endpoints from %s on %s://%s\n", len(endpoints), dir, scheme, addr)
+	for _, endpoint := range endpoints {
+		fmt.Printf("  %-7s %s (%s)\n", endpoint.Method, endpoint.Path, endpoint.Name)
+	}
+	if upstream != "" {
+		fmt.Printf("Proxying unmatched requests to %s\n", upstream)
+	}
+	fmt.Printf("Admin API available on %s://%s%s\n", scheme, addr, server.AdminPrefix)
+	if watch {
+		fmt.Printf("Watching %s for changes\n", dir)
+	}
+
+	httpServer := &http.Server{Addr: addr, Handler: srv, TLSConfig: tlsConfig}
+	if tlsConfig != nil {
+		err = httpServer.ListenAndServeTLS("", "")
+	} else {
+		err = httpServer.ListenAndServe()
+	}
+	if err != nil {
+		fmt.Println("Error serving endpoints:", err)
+		os.Exit(1)
+	}
+}
+
+// mtlsEndpoints returns the names of the endpoints authenticating clients by certificate
+func mtlsEndpoints(endpoints []*server.Endpoint) []string {
+	var names []string
+	for _, endpoint := range endpoints {
+		if authType, _ := endpoint.Auth["type"].(string); authType == "mtls" {
+			names = append(names, endpoint.Name)
+		}
+	}
+	return names
+}
+
+// checkNoMTLS refuses endpoints using mtls auth, served without --client-ca
+func checkNoMTLS(endpoints []*server.Endpoint) error {
+	if names := mtlsEndpoints(endpoints); len(names) > 0 {
+		return fmt.Errorf("endpoints %s use mtls auth, which requires --client-ca", strings.Join(names, ", "))
+	}
+	return nil
+}
+
+// serverCORS builds the server CORS policy from the CORS flags, or returns nil when none is set
+func serverCORS(cmd *cobra.Command) *server.CORS {
+	enabled := false
+	for _, name := range []string{"cors-origins", "cors-methods", "cors-headers", "cors-credentials", "cors-max-age"} {
+		enabled = enabled || cmd.Flags().Changed(name)
+	}
+	if !enabled {
+		return nil
+	}
+
+	cors := &server.CORS{}
+	cors.Origins, _ = cmd.Flags().GetStringSlice("cors-origins")
+	cors.Methods, _ = cmd.Flags().GetStringSlice("cors-methods")
+	cors.Headers, _ = cmd.Flags().GetStringSlice("cors-headers")
+	cors.Credentials, _ = cmd.Flags().GetBool("cors-credentials")
+	cors.MaxAge, _ = cmd.Flags().GetInt("cors-max-age")
+	return cors
+}
+
+// serverTLSConfig builds the TLS configuration from the TLS flags, or returns nil to serve plain HTTP
+func serverTLSConfig(cmd *cobra.Command, host string) (*tls.Config, error) {
+	generate, _ := cmd.Flags().GetBool("tls")
+	caFile, _ := cmd.Flags().GetString("ca-file")
+	certFile, _ := cmd.Flags().GetString("cert")
+	keyFile, _ := cmd.Flags().GetString("key")
+	clientCAFile, _ := cmd.Flags().GetString("client-ca")
+
+	if (certFile == "") != (keyFile == "") {
+		return nil, fmt.Errorf("--cert and --key must be used together")
+	}
+	if !generate && certFile == "" {
+		if clientCAFile != "" {
+			return nil, fmt.Errorf("--client-ca requires --tls or --cert and --key")
+		}
+		return nil, nil
+	}
+
+	var certificate tls.Certificate
+	var err error
+	if certFile != "" {
+		certificate, err = tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		hosts := []string{"localhost", "127.0.0.1", "::1"}
+		if host != "" && !slices.Contains(hosts, host) {
+			hosts = append([]string{host}, hosts...)
+		}
+
+		var caPEM []byte
+		certificate, caPEM, err = server.GenerateCertificate(hosts)
+		if err != nil {
+			return nil, err
+		}
+		if err := utils.WriteFile(caFile, string(caPEM)); err != nil {
+			return nil, err
+		}
+		fmt.Printf("Wrote the CA certificate to %s, add it to your trust store to trust the server\n", caFile)
+	}
+
+	var clientCAs *x509.CertPool
+	if clientCAFile != "" {
+		pemData, err := utils.LoadFile(clientCAFile)
+		if err != nil {
+			return nil, err
+		}
+		clientCAs, err = server.LoadCertPool([]byte(pemData))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", clientCAFile, err)
+		}
+		fmt.Printf("Requiring client certificates signed by %s\n", clientCAFile)
+	}
+
+	return server.TLSConfig(certificate, clientCAs), nil
+}
+
+// loadEndpoints reads every endpoint file under dir. Each endpoint is named after
+// its path relative to dir, without the extension.
+func loadEndpoints(dir string, vars map[string]string) ([]*server.Endpoint, error) {
+	paths, err := findEndpointFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	endpoints := make([]*server.Endpoint, 0, len(paths))
+	for _, path := range paths {
+		endpoint, err := loadEndpoint(dir, path, vars)
+		if err != nil {
+			return nil, err
+		}
+		endpoints = append(endpoints, endpoint)
+	}
+	return endpoints, nil
+}
+
+// findEndpointFiles returns the endpoint files under dir, skipping hidden directories, and
+// the files and directories named with a leading _, which hold fragments files include
+func findEndpointFiles(dir string) ([]string, error) {
+	var paths []string
+
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path != dir && (strings.HasPrefix(d.Name(), ".") || strings.HasPrefix(d.Name(), "_")) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if isEndpointFile(path) && !strings.HasPrefix(d.Name(), "_") {
+			paths = append(paths, path)
+		}
+		return nil
+	})
+
+	return paths, err
+}
+
+func loadEndpoint(dir, path string, vars map[string]string) (*server.Endpoint, error) {
+	data, err := readEndpointFile(path, vars)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return server.NewEndpoint(endpointName(dir, path), data)
+}
+
+func endpointName(dir, path string) string {
+	name, err := filepath.Rel(dir, path)
+	if err != nil {
+		name = filepath.Base(path)
+	}
+	name = strings.TrimSuffix(name, filepath.Ext(name))
+	// Files named after the *.mock.yml editor convention are served without the suffix
+	name = strings.TrimSuffix(name, ".mock")
+	return filepath.ToSlash(name)
+}
+
+func isEndpointFile(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yml", ".yaml", ".json":
+		return true
+	}
+	return false
+}
+
+// parseServerURL parses an absolute http(s) URL given on the command line
+func parseServerURL(raw string) (*url.URL, error) {
+	parsed, err := url.Parse(raw)
+	if err != nil {
+		return nil, err
+	}
+	if (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return nil, fmt.Errorf("%s is not an http or https URL", raw)
+	}
+	return parsed, nil
+}
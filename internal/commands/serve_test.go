@@ -0,0 +1,29 @@
+package commands
+
+import (
+	"testing"
+
+	"github.com/nicobistolfi/mockthis-cli/internal/server"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMTLSEndpoints(t *testing.T) {
+	var endpoints []*server.Endpoint
+	for name, auth := range map[string]interface{}{
+		"billing": map[string]interface{}{"type": "mtls", "properties": map[string]interface{}{"subject": "billing"}},
+		"orders":  map[string]interface{}{"type": "bearer", "properties": map[string]interface{}{"token": "secret"}},
+		"public":  nil,
+	} {
+		data := map[string]interface{}{"response": map[string]interface{}{"body": name}}
+		if auth != nil {
+			data["auth"] = auth
+		}
+		endpoint, err := server.NewEndpoint(name, data)
+		assert.NoError(t, err)
+		endpoints = append(endpoints, endpoint)
+	}
+
+	assert.Equal(t, []string{"billing"}, mtlsEndpoints(endpoints))
+	assert.EqualError(t, checkNoMTLS(endpoints), "endpoints billing use mtls auth, which requires --client-ca")
+	assert.NoError(t, checkNoMTLS(nil))
+}
@@ -0,0 +1,130 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/nicobistolfi/mockthis-cli/internal/state"
+	"github.com/nicobistolfi/mockthis-cli/internal/utils"
+)
+
+// recordEndpoint records the endpoint created or pulled from an endpoint file in the state
+// of the working directory when the file is in it, for its name to resolve from there, or
+// else in the state of the directory of the file
+func recordEndpoint(filePath, id, mockURL string) error {
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return err
+	}
+	absPath, err := filepath.Abs(filePath)
+	if err != nil {
+		return err
+	}
+
+	root := state.Root(".")
+	if rel, err := filepath.Rel(root, absPath); err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		root = state.Root(filepath.Dir(absPath))
+	}
+	return state.Update(root, func(s *state.State) error {
+		s.Put(state.Entry{
+			File:    s.Rel(absPath),
+			Name:    endpointName(root, absPath),
+			ID:      id,
+			MockURL: mockURL,
+			Hash:    state.Hash(content),
+		})
+		return nil
+	})
+}
+
+// forgetEndpoints removes deleted endpoints from the states resolveEndpointRef reads: the
+// state of the working directory and those of the endpoint files among refs
+func forgetEndpoints(ids, refs []string) error {
+	roots := []string{state.Root(".")}
+	seen := map[string]bool{roots[0]: true}
+	for _, ref := range refs {
+		if exists, _ := utils.FileExists(ref); exists && isEndpointFile(ref) {
+			if root := state.Root(filepath.Dir(ref)); !seen[root] {
+				seen[root] = true
+				roots = append(roots, root)
+			}
+		}
+	}
+
+	for _, root := range roots {
+		s, err := state.Load(root)
+		if err != nil {
+			return err
+		}
+		if len(s.Endpoints) == 0 {
+			continue
+		}
+		err = state.Update(root, func(s *state.State) error {
+			for _, id := range ids {
+				s.RemoveID(id)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// resolveEndpointRef returns the ID of the endpoint created from an endpoint file, given by
+// its path or by its name in the state of the working directory. Other references are
+// returned as they are, as IDs.
+func resolveEndpointRef(ref string) (string, error) {
+	if exists, _ := utils.FileExists(ref); exists && isEndpointFile(ref) {
+		s, err := state.Load(state.Root(filepath.Dir(ref)))
+		if err != nil {
+			return "", err
+		}
+		entry, ok := s.ByFile(ref)
+		if !ok {
+			return "", fmt.Errorf("no endpoint was created from %s, create it with 'mockthis create --file %s' or pull it", ref, ref)
+		}
+		return entry.ID, nil
+	}
+
+	s, err := state.Load(state.Root("."))
+	if err != nil {
+		return "", err
+	}
+	entries := s.ByName(ref)
+	switch len(entries) {
+	case 0:
+		return ref, nil
+	case 1:
+		return entries[0].ID, nil
+	}
+	return "", fmt.Errorf("%s names %d endpoints, use the path of its file or its ID", ref, len(entries))
+}
+
+// stateID returns the ID of the endpoint created from an endpoint file, or ""
+func stateID(filePath string) string {
+	return stateEntry(filePath).ID
+}
+
+// stateEntry returns the state entry of an endpoint file, empty when it has none
+func stateEntry(filePath string) state.Entry {
+	s, err := state.Load(state.Root(filepath.Dir(filePath)))
+	if err != nil {
+		return state.Entry{}
+	}
+	entry, _ := s.ByFile(filePath)
+	return entry
+}
+
+// editedSinceRecorded reports whether an endpoint file changed since it was created or
+// pulled, comparing it with the hash of the state entry
+func editedSinceRecorded(filePath string, entry state.Entry) bool {
+	if entry.Hash == "" {
+		return false
+	}
+	content, err := os.ReadFile(filePath)
+	return err == nil && state.Hash(content) != entry.Hash
+}
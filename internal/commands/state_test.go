@@ -0,0 +1,114 @@
+package commands
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/nicobistolfi/mockthis-cli/internal/state"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolveEndpointRef(t *testing.T) {
+	// The temporary directory can be a symlink, as on macOS
+	dir, err := filepath.EvalSymlinks(t.TempDir())
+	assert.NoError(t, err)
+	chdir(t, dir)
+	assert.NoError(t, os.MkdirAll(filepath.Join("mocks", "v2"), 0755))
+	for _, name := range []string{"mocks/users.yml", "mocks/v2/users.yml", "mocks/charges.yml"} {
+		assert.NoError(t, os.WriteFile(name, []byte("path: /users\n"), 0644))
+	}
+
+	// Without a state, references are IDs
+	id, err := resolveEndpointRef("users")
+	assert.NoError(t, err)
+	assert.Equal(t, "users", id)
+	_, err = resolveEndpointRef("mocks/users.yml")
+	assert.Error(t, err)
+
+	assert.NoError(t, recordEndpoint("mocks/users.yml", "u1", "https://mock.example.com/u1"))
+	assert.NoError(t, recordEndpoint(filepath.Join(dir, "mocks/v2/users.yml"), "u2", ""))
+
+	// The state is in the working directory, which holds the files
+	s, err := state.Load(dir)
+	assert.NoError(t, err)
+	assert.Len(t, s.Endpoints, 2)
+	assert.Equal(t, "mocks/users.yml", s.Endpoints[0].File)
+	assert.Equal(t, "mocks/users", s.Endpoints[0].Name)
+	assert.Equal(t, "https://mock.example.com/u1", s.Endpoints[0].MockURL)
+
+	for ref, want := range map[string]string{
+		"mocks/users.yml":    "u1",
+		"./mocks/users.yml":  "u1",
+		"mocks/v2/users.yml": "u2",
+		"mocks/users":        "u1",
+		"mocks/v2/users":     "u2",
+		"1a2b3c":             "1a2b3c",
+	} {
+		id, err := resolveEndpointRef(ref)
+		assert.NoError(t, err, ref)
+		assert.Equal(t, want, id, ref)
+	}
+	assert.Equal(t, "u2", stateID("mocks/v2/users.yml"))
+	assert.Equal(t, "", stateID("mocks/charges.yml"))
+
+	// Files not recorded aren't taken for IDs
+	_, err = resolveEndpointRef("mocks/charges.yml")
+	assert.Error(t, err)
+
+	// Deleted endpoints are forgotten
+	assert.NoError(t, forgetEndpoints([]string{"u1", "unknown"}, nil))
+	id, err = resolveEndpointRef("mocks/users")
+	assert.NoError(t, err)
+	assert.Equal(t, "mocks/users", id)
+	assert.Equal(t, "u2", stateID("mocks/v2/users.yml"))
+}
+
+func TestForgetEndpointsOfOtherRoots(t *testing.T) {
+	// The temporary directory can be a symlink, as on macOS
+	dir, err := filepath.EvalSymlinks(t.TempDir())
+	assert.NoError(t, err)
+	work, other := filepath.Join(dir, "work"), filepath.Join(dir, "other")
+	for _, d := range []string{work, other} {
+		assert.NoError(t, os.MkdirAll(d, 0755))
+	}
+	chdir(t, work)
+	filePath := filepath.Join(other, "users.yml")
+	assert.NoError(t, os.WriteFile(filePath, []byte("path: /users\n"), 0644))
+	assert.NoError(t, recordEndpoint(filePath, "u1", ""))
+	assert.Equal(t, "u1", stateID(filePath))
+
+	// An endpoint deleted by the path of its file is forgotten by the state of the file
+	assert.NoError(t, forgetEndpoints([]string{"u1"}, []string{filePath}))
+	assert.Equal(t, "", stateID(filePath))
+	_, err = os.Stat(filepath.Join(work, state.Dir))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestResolveAmbiguousName(t *testing.T) {
+	// The temporary directory can be a symlink, as on macOS
+	dir, err := filepath.EvalSymlinks(t.TempDir())
+	assert.NoError(t, err)
+	chdir(t, dir)
+	assert.NoError(t, state.Update(dir, func(s *state.State) error {
+		s.Put(state.Entry{File: "a/users.yml", Name: "users", ID: "u1"})
+		s.Put(state.Entry{File: "a/users.json", Name: "users", ID: "u2"})
+		return nil
+	}))
+
+	_, err = resolveEndpointRef("users")
+	assert.Error(t, err)
+}
+
+func TestDeletedIDs(t *testing.T) {
+	failures := []bulkFailure{{ID: "b", Err: assert.AnError}}
+	assert.Equal(t, []string{"a", "c"}, deletedIDs([]string{"a", "b", "c"}, failures))
+}
+
+// chdir changes the working directory for the duration of a test
+func chdir(t *testing.T, dir string) {
+	wd, err := os.Getwd()
+	assert.NoError(t, err)
+	assert.NoError(t, os.Chdir(dir))
+	t.Cleanup(func() { os.Chdir(wd) })
+}
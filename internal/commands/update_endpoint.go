@@ -5,17 +5,36 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/url"
+	"os"
 
 	"github.com/nicobistolfi/mockthis-cli/internal/config"
+	"github.com/nicobistolfi/mockthis-cli/internal/labels"
 	"github.com/spf13/cobra"
 )
 
-// UpdateEndpointCmd is the command to update an existing mock endpoint
+// UpdateEndpointCmd is the command to update existing mock endpoints
 var UpdateEndpointCmd = &cobra.Command{
-	Use:   "update [id]",
-	Short: "Update an existing mock endpoint",
-	Args:  cobra.ExactArgs(1),
-	Run:   updateEndpoint,
+	Use:   "update [id, name or file...] | - | --all | --selector <selector>",
+	Short: "Update existing mock endpoints",
+	Long: `Update an existing mock endpoint, prompting for its new status, content type, charset
+and body.
+
+With --label, only the labels are updated: key=value sets a label and key- removes it. Labels
+are updated on every endpoint given by ID, read from stdin with -, or selected with --all or
+--selector, the way delete selects endpoints. The endpoints are updated concurrently, and the
+failures are reported at the end.`,
+	Example: `  mockthis update 1a2b3c
+  mockthis update 1a2b3c 4d5e6f --label env=staging
+  mockthis update --selector team=payments --label owner=payments,old-`,
+	Args: cobra.ArbitraryArgs,
+	Run:  updateEndpoint,
+}
+
+func init() {
+	UpdateEndpointCmd.Flags().StringSliceP("label", "l", nil, "Labels to set as key=value, or to remove as key-. Eg. 'env=ci,old-'")
+	addSelectionFlags(UpdateEndpointCmd, "update", "")
+	addBulkFlags(UpdateEndpointCmd)
 }
 
 func updateEndpoint(cmd *cobra.Command, args []string) {
@@ -24,9 +43,23 @@ func updateEndpoint(cmd *cobra.Command, args []string) {
 		fmt.Println("You need to login first.")
 		return
 	}
+
+	if labelFlags, _ := cmd.Flags().GetStringSlice("label"); len(labelFlags) > 0 {
+		updateEndpointLabels(cmd, configData, args, labelFlags)
+		return
+	}
+	if len(args) != 1 || args[0] == "-" || cmd.Flags().Changed("selector") || cmd.Flags().Changed("all") {
+		fmt.Println("Only labels can be updated on many endpoints, give one endpoint ID or --label.")
+		os.Exit(1)
+	}
 	token := configData.Token
 
-	id := args[0]
+	id, err := resolveEndpointRef(args[0])
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
 	status := promptForInput("Enter new HTTP status code: ")
 	responseContentType := promptForInput("Enter new response Content-Type: ")
 	charset := promptForInput("Enter new charset: ")
@@ -59,3 +92,66 @@ func updateEndpoint(cmd *cobra.Command, args []string) {
 
 	fmt.Println("Endpoint updated successfully!")
 }
+
+// updateEndpointLabels sets and removes the labels of --label on the endpoints of bulkIDs
+func updateEndpointLabels(cmd *cobra.Command, configData *config.Data, args, changes []string) {
+	set, remove, err := labels.Parse(changes)
+	if err != nil {
+		fmt.Println("Error updating labels:", err)
+		os.Exit(1)
+	}
+	options, err := bulkFlags(cmd)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	ids, err := bulkIDs(cmd, configData, args)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	if len(ids) == 0 {
+		fmt.Println("No endpoints to update")
+		return
+	}
+	endpoints, err := fetchEndpoints(configData.Token, "")
+	if err != nil {
+		fmt.Println("Error fetching endpoints:", err)
+		os.Exit(1)
+	}
+
+	failures := updateLabels(configData.Token, endpoints, ids, set, remove, options)
+	if len(ids) == 1 && len(failures) == 0 {
+		fmt.Println("Endpoint updated successfully!")
+		return
+	}
+	printBulkSummary(os.Stdout, "Updated", len(ids), failures)
+	if len(failures) > 0 {
+		os.Exit(1)
+	}
+}
+
+// updateLabels sets and removes labels on the endpoints with the given IDs through runBulk,
+// sending each endpoint its labels after the changes
+func updateLabels(token string, endpoints []map[string]interface{}, ids []string, set map[string]string, remove []string, options bulkOptions) []bulkFailure {
+	return runBulk(ids, options, progressWriter(), func(id string) error {
+		endpoint := findEndpoint(endpoints, id)
+		if endpoint == nil {
+			return fmt.Errorf("endpoint not found")
+		}
+		body := map[string]interface{}{"labels": changedLabels(endpoint, set, remove)}
+		return apiRequest(token, http.MethodPatch, "/endpoints/"+url.PathEscape(stringField(endpoint, "id")), body, nil)
+	})
+}
+
+// changedLabels returns the labels of an endpoint after setting and removing labels
+func changedLabels(endpoint map[string]interface{}, set map[string]string, remove []string) map[string]string {
+	endpointLabels := labels.FromValue(endpoint["labels"])
+	for _, key := range remove {
+		delete(endpointLabels, key)
+	}
+	for key, value := range set {
+		endpointLabels[key] = value
+	}
+	return endpointLabels
+}
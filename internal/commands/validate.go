@@ -0,0 +1,253 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/nicobistolfi/mockthis-cli/internal/utils"
+	"github.com/spf13/cobra"
+)
+
+// ValidateCmd is the command to validate and lint endpoint files
+var ValidateCmd = &cobra.Command{
+	Use:   "validate [files or directories...]",
+	Short: "Validate and lint endpoint files",
+	Long: `Validate endpoint files against the endpoint file schema and lint them.
+
+Each problem is reported with the file, line and column it was found at. Besides the
+schema, the lint rules check that the response body matches its content type and
+schema, that the status is between 100 and 599, that auth blocks have the credentials
+their type needs, and that no two files serve the same method and path.
+
+The command exits with a non-zero status when a problem is found.`,
+	Run: validate,
+}
+
+func init() {
+	ValidateCmd.Flags().String("format", "text", "Report format: text, json or sarif")
+	addVarFlag(ValidateCmd)
+}
+
+func validate(cmd *cobra.Command, args []string) {
+	format, _ := cmd.Flags().GetString("format")
+	if format != "text" && format != "json" && format != "sarif" {
+		fmt.Println("Invalid format. Use text, json or sarif.")
+		os.Exit(1)
+	}
+
+	vars, err := varFlags(cmd)
+	if err != nil {
+		fmt.Println("Invalid variable:", err)
+		os.Exit(1)
+	}
+
+	if len(args) == 0 {
+		args = []string{"."}
+	}
+
+	diagnostics, files, err := lintPaths(args, vars)
+	if err != nil {
+		fmt.Println("Error reading endpoint files:", err)
+		os.Exit(1)
+	}
+
+	switch format {
+	case "json":
+		err = writeJSONReport(os.Stdout, diagnostics)
+	case "sarif":
+		err = writeSARIFReport(os.Stdout, diagnostics)
+	default:
+		for _, d := range diagnostics {
+			fmt.Println(d)
+		}
+		if len(diagnostics) == 0 {
+			fmt.Printf("%d endpoint files are valid\n", files)
+		} else {
+			fmt.Printf("%d problems found in %d endpoint files\n", len(diagnostics), files)
+		}
+	}
+	if err != nil {
+		fmt.Println("Error writing report:", err)
+		os.Exit(1)
+	}
+
+	if len(diagnostics) > 0 {
+		os.Exit(1)
+	}
+}
+
+// lintPaths lints the endpoint files given and those under the directories given. Routes are
+// named relative to the directory given, as serve names them. vars are the --var variables.
+func lintPaths(paths []string, vars map[string]string) ([]diagnostic, int, error) {
+	var diagnostics []diagnostic
+	routes := make(map[string]string)
+	files := 0
+
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, files, err
+		}
+
+		dir, filePaths := filepath.Dir(path), []string{path}
+		if info.IsDir() {
+			dir = path
+			if filePaths, err = findEndpointFiles(path); err != nil {
+				return nil, files, err
+			}
+		}
+
+		for _, filePath := range filePaths {
+			files++
+			content, err := utils.LoadFile(filePath)
+			if err != nil {
+				return nil, files, err
+			}
+
+			doc, syntax := parseEndpointDocument(filePath, content, vars)
+			if doc == nil {
+				diagnostics = append(diagnostics, syntax...)
+				continue
+			}
+
+			if schema := doc.schemaDiagnostics(); len(schema) > 0 {
+				diagnostics = append(diagnostics, schema...)
+				continue
+			}
+			diagnostics = append(diagnostics, doc.lintDiagnostics()...)
+
+			route, ok := routeKey(endpointName(dir, filePath), doc.data)
+			if !ok {
+				continue
+			}
+			if first, exists := routes[route]; exists {
+				diagnostics = append(diagnostics, doc.diagnostic(ruleDuplicateRoute, fmt.Sprintf("%s is also served by %s", route, first), "endpoint", "path"))
+			} else {
+				routes[route] = filePath
+			}
+		}
+	}
+
+	sort.SliceStable(diagnostics, func(i, j int) bool {
+		if diagnostics[i].File != diagnostics[j].File {
+			return diagnostics[i].File < diagnostics[j].File
+		}
+		return diagnostics[i].Line < diagnostics[j].Line
+	})
+	return diagnostics, files, nil
+}
+
+func writeJSONReport(w io.Writer, diagnostics []diagnostic) error {
+	if diagnostics == nil {
+		diagnostics = []diagnostic{}
+	}
+	encoded, err := json.MarshalIndent(diagnostics, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(w, string(encoded))
+	return err
+}
+
+type sarifLog struct {
+	Version string     `json:"version"`
+	Schema  string     `json:"$schema"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           sarifRegion           `json:"region"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn"`
+}
+
+// writeSARIFReport writes the diagnostics as a SARIF 2.1.0 log, the format GitHub code
+// scanning reads
+func writeSARIFReport(w io.Writer, diagnostics []diagnostic) error {
+	ids := make([]string, 0, len(lintRules))
+	for id := range lintRules {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	run := sarifRun{
+		Tool: sarifTool{Driver: sarifDriver{
+			Name:           "mockthis",
+			InformationURI: "https://github.com/nicobistolfi/mockthis-cli",
+		}},
+		Results: []sarifResult{},
+	}
+	for _, id := range ids {
+		run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, sarifRule{ID: id, ShortDescription: sarifMessage{Text: lintRules[id]}})
+	}
+
+	for _, d := range diagnostics {
+		run.Results = append(run.Results, sarifResult{
+			RuleID:  d.Rule,
+			Level:   "error",
+			Message: sarifMessage{Text: d.Message},
+			Locations: []sarifLocation{{PhysicalLocation: sarifPhysicalLocation{
+				ArtifactLocation: sarifArtifactLocation{URI: filepath.ToSlash(d.File)},
+				Region:           sarifRegion{StartLine: d.Line, StartColumn: d.Column},
+			}}},
+		})
+	}
+
+	encoded, err := json.MarshalIndent(sarifLog{
+		Version: "2.1.0",
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Runs:    []sarifRun{run},
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(w, string(encoded))
+	return err
+}
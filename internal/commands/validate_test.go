@@ -0,0 +1,134 @@
+package commands
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writeEndpointFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		assert.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+	return dir
+}
+
+func TestLintPaths(t *testing.T) {
+	dir := writeEndpointFiles(t, map[string]string{
+		"valid.yml": `endpoint:
+  response:
+    body: '{"ok": true}'
+`,
+		"schema.yml": `endpoint:
+  response:
+    status: "200"
+    colour: red
+`,
+		"body.yml": `endpoint:
+  path: /body
+  response:
+    content-type: application/json
+    schema:
+      type: object
+      required: [id]
+    body: '{"name": "x"}'
+`,
+		"xml.yml": `endpoint:
+  path: /xml
+  response:
+    content-type: application/xml
+    body: '<user><name>x</user>'
+`,
+		"status.json": `{"endpoint": {"path": "/status", "response": {"status": "99"}}}`,
+		"auth.yml": `endpoint:
+  path: /auth
+  auth:
+    type: mtls
+    properties: {}
+  response: {}
+`,
+		"syntax.json": "{\n  \"endpoint\": {\n    \"response\": {,}\n  }\n}",
+		"nested/valid.yml": `endpoint:
+  path: /valid
+  response: {}
+`,
+	})
+
+	diagnostics, files, err := lintPaths([]string{dir}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 8, files)
+
+	found := make(map[string]diagnostic)
+	for _, d := range diagnostics {
+		rel, _ := filepath.Rel(dir, d.File)
+		found[filepath.ToSlash(rel)+" "+d.Rule] = d
+	}
+
+	expected := map[string][2]int{
+		"schema.yml endpoint-schema":  {4, 5},
+		"body.yml body-schema":        {8, 5},
+		"xml.yml body-content-type":   {5, 5},
+		"status.json endpoint-schema": {1, 47},
+		"auth.yml endpoint-schema":    {5, 5},
+		"syntax.json syntax":          {3, 18},
+		"valid.yml duplicate-route":   {1, 1},
+	}
+	for key, position := range expected {
+		d, ok := found[key]
+		if assert.True(t, ok, "missing %s in %v", key, diagnostics) {
+			assert.Equal(t, position, [2]int{d.Line, d.Column}, key)
+		}
+	}
+	_, ok := found["valid.yml endpoint-schema"]
+	assert.False(t, ok)
+}
+
+func TestLintRules(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		rule    string
+	}{
+		{"JSON body for JSON content type", `{"endpoint": {"response": {"body": "not json"}}}`, ruleBodyContentType},
+		{"object body for text content type", "endpoint:\n  response:\n    content-type: text/plain\n    body:\n      id: 1\n", ruleBodyContentType},
+		{"scenario body against base schema", "endpoint:\n  response:\n    schema: {type: object, required: [id]}\n    body: '{\"id\": 1}'\n  scenario:\n    name: s\n    responses:\n      - state: Started\n        response:\n          body: '{}'\n", ruleBodySchema},
+		{"parameter inside a segment", "endpoint:\n  path: /users/id-{id}\n  response: {}\n", rulePathPattern},
+		{"basic auth without password", "endpoint:\n  auth:\n    type: basic\n    properties:\n      username: u\n      password: ''\n  response: {}\n", ruleAuthProperties},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, syntax := parseEndpointDocument("test.yml", tt.content, nil)
+			assert.Empty(t, syntax)
+			assert.Empty(t, doc.schemaDiagnostics())
+
+			diagnostics := doc.lintDiagnostics()
+			if assert.Len(t, diagnostics, 1) {
+				assert.Equal(t, tt.rule, diagnostics[0].Rule)
+			}
+		})
+	}
+}
+
+func TestWriteSARIFReport(t *testing.T) {
+	var buf bytes.Buffer
+	err := writeSARIFReport(&buf, []diagnostic{{File: "mocks/a.yml", Line: 3, Column: 5, Rule: ruleStatusRange, Message: "status 700 is not between 100 and 599"}})
+	assert.NoError(t, err)
+
+	var log sarifLog
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &log))
+	assert.Equal(t, "2.1.0", log.Version)
+	assert.Len(t, log.Runs[0].Tool.Driver.Rules, len(lintRules))
+	result := log.Runs[0].Results[0]
+	assert.Equal(t, ruleStatusRange, result.RuleID)
+	assert.Equal(t, "mocks/a.yml", result.Locations[0].PhysicalLocation.ArtifactLocation.URI)
+	assert.Equal(t, 3, result.Locations[0].PhysicalLocation.Region.StartLine)
+}
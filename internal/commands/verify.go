@@ -0,0 +1,244 @@
+package commands
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// VerifyCmd is the command to check a real service against endpoint files
+var VerifyCmd = &cobra.Command{
+	Use:   "verify --target <url> [--dir <path>] [--junit <path>]",
+	Short: "Check that a real service responds as its endpoint files promise",
+	Long: `Check that a real service responds as its endpoint files promise.
+
+Each endpoint file is turned into a request sent to the target service, and the response is
+checked against the status, content type, headers and response schema of the file. Headers
+only have to be present, their values are not compared. The command exits with a non-zero
+status when an endpoint fails.`,
+	Args: cobra.NoArgs,
+	Run:  verify,
+}
+
+func init() {
+	VerifyCmd.Flags().String("target", "", "URL of the service to verify (required)")
+	VerifyCmd.Flags().StringP("dir", "d", ".", "Directory containing JSON or YAML endpoint files")
+	VerifyCmd.Flags().String("junit", "", "Path to write a JUnit XML report to")
+	VerifyCmd.Flags().StringArrayP("header", "H", nil, "Header to send with every request, replacing the credentials of the files. Eg. 'Authorization: Bearer <token>'")
+	addClientFlags(VerifyCmd)
+	addVarFlag(VerifyCmd)
+	_ = VerifyCmd.MarkFlagRequired("target")
+}
+
+// verifyResult is the outcome of verifying one endpoint file
+type verifyResult struct {
+	Name     string
+	Request  string
+	Checks   []check
+	Err      error
+	Duration time.Duration
+}
+
+func (r verifyResult) failed() bool {
+	if r.Err != nil {
+		return true
+	}
+	for _, c := range r.Checks {
+		if c.Err != nil {
+			return true
+		}
+	}
+	return false
+}
+
+func verify(cmd *cobra.Command, args []string) {
+	target, _ := cmd.Flags().GetString("target")
+	dir, _ := cmd.Flags().GetString("dir")
+	junitPath, _ := cmd.Flags().GetString("junit")
+	headerFlags, _ := cmd.Flags().GetStringArray("header")
+
+	if _, err := parseServerURL(target); err != nil {
+		fmt.Println("Invalid target:", err)
+		os.Exit(1)
+	}
+
+	extra, err := parseHeaderFlags(headerFlags)
+	if err != nil {
+		fmt.Println("Invalid header:", err)
+		os.Exit(1)
+	}
+
+	vars, err := varFlags(cmd)
+	if err != nil {
+		fmt.Println("Invalid variable:", err)
+		os.Exit(1)
+	}
+
+	client, err := contractClient(cmd)
+	if err != nil {
+		fmt.Println("Error configuring client:", err)
+		os.Exit(1)
+	}
+
+	paths, err := findEndpointFiles(dir)
+	if err != nil {
+		fmt.Println("Error reading endpoint files:", err)
+		os.Exit(1)
+	}
+	if len(paths) == 0 {
+		fmt.Println("No endpoint files found in", dir)
+		os.Exit(1)
+	}
+
+	results := make([]verifyResult, 0, len(paths))
+	failed := 0
+	for _, path := range paths {
+		result := verifyEndpoint(client, target, extra, dir, path, vars)
+		results = append(results, result)
+		if result.failed() {
+			failed++
+		}
+		printVerifyResult(os.Stdout, result)
+	}
+
+	if junitPath != "" {
+		if err := writeJUnitReport(junitPath, results); err != nil {
+			fmt.Println("Error writing JUnit report:", err)
+			os.Exit(1)
+		}
+	}
+
+	if failed > 0 {
+		fmt.Printf("%d of %d endpoints failed verification\n", failed, len(results))
+		os.Exit(1)
+	}
+	fmt.Printf("All %d endpoints verified\n", len(results))
+}
+
+func verifyEndpoint(client *http.Client, target string, extra http.Header, dir, path string, vars map[string]string) verifyResult {
+	result := verifyResult{Name: endpointName(dir, path)}
+
+	c, err := contractFromFile(dir, path, vars)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+
+	start := time.Now()
+	req, resp, checks, err := c.exchange(client, target, extra, false)
+	result.Duration = time.Since(start)
+	if req != nil {
+		result.Request = req.Method + " " + req.URL.String()
+	}
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	result.Request += " -> " + resp.Status
+	result.Checks = checks
+	return result
+}
+
+// parseHeaderFlags parses "Name: value" headers given on the command line
+func parseHeaderFlags(headers []string) (http.Header, error) {
+	parsed := http.Header{}
+	for _, header := range headers {
+		name, value, ok := strings.Cut(header, ":")
+		if !ok || strings.TrimSpace(name) == "" {
+			return nil, fmt.Errorf("%q is not a 'Name: value' header", header)
+		}
+		parsed.Add(strings.TrimSpace(name), strings.TrimSpace(value))
+	}
+	return parsed, nil
+}
+
+func printVerifyResult(w io.Writer, result verifyResult) {
+	if result.Request == "" {
+		fmt.Fprintln(w, result.Name)
+	} else {
+		fmt.Fprintf(w, "%s: %s\n", result.Name, result.Request)
+	}
+	if result.Err != nil {
+		fmt.Fprintf(w, "  ERROR %v\n", result.Err)
+		return
+	}
+	printChecks(w, result.Checks)
+}
+
+type junitTestSuite struct {
+	XMLName  xml.Name        `xml:"testsuite"`
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Errors   int             `xml:"errors,attr"`
+	Time     string          `xml:"time,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	Classname string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitMessage `xml:"failure,omitempty"`
+	Error     *junitMessage `xml:"error,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// junitReport builds a test suite with a test case per endpoint file. Failed checks are
+// failures, and files that could not be loaded or requested are errors.
+func junitReport(results []verifyResult) junitTestSuite {
+	suite := junitTestSuite{Name: "mockthis verify", Tests: len(results)}
+	var total time.Duration
+
+	for _, result := range results {
+		total += result.Duration
+		testCase := junitTestCase{
+			Name:      result.Name,
+			Classname: "mockthis.verify",
+			Time:      fmt.Sprintf("%.3f", result.Duration.Seconds()),
+			SystemOut: result.Request,
+		}
+
+		if result.Err != nil {
+			suite.Errors++
+			testCase.Error = &junitMessage{Message: result.Err.Error(), Text: result.Err.Error()}
+		} else {
+			var failures []string
+			for _, c := range result.Checks {
+				if c.Err != nil {
+					failures = append(failures, fmt.Sprintf("%s: %v", c.Name, c.Err))
+				}
+			}
+			if len(failures) > 0 {
+				suite.Failures++
+				testCase.Failure = &junitMessage{
+					Message: fmt.Sprintf("%d checks failed", len(failures)),
+					Text:    strings.Join(failures, "\n"),
+				}
+			}
+		}
+		suite.Cases = append(suite.Cases, testCase)
+	}
+
+	suite.Time = fmt.Sprintf("%.3f", total.Seconds())
+	return suite
+}
+
+func writeJUnitReport(path string, results []verifyResult) error {
+	encoded, err := xml.MarshalIndent(junitReport(results), "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append([]byte(xml.Header), append(encoded, '\n')...), 0644)
+}
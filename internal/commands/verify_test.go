@@ -0,0 +1,87 @@
+package commands
+
+import (
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerify(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"users.yml": `endpoint:
+  path: /users
+  response:
+    headers:
+      X-Request-Id: "mocked"
+    schema: {"type": "object", "required": ["id"]}
+    body: '{"id": "1"}'
+`,
+		"orders.yml": `endpoint:
+  path: /orders
+  response:
+    status: "201"
+    method: POST
+`,
+		"broken.yml": `endpoint: {}
+`,
+	}
+	for name, content := range files {
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+	}
+
+	var authorization string
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization = r.Header.Get("Authorization")
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-Request-Id", "real")
+		if r.URL.Path == "/orders" {
+			w.WriteHeader(http.StatusBadRequest)
+		}
+		_, _ = io.WriteString(w, `{"id": "2"}`)
+	}))
+	defer target.Close()
+
+	extra := http.Header{"Authorization": {"Bearer real"}}
+	var results []verifyResult
+	for _, name := range []string{"users.yml", "orders.yml", "broken.yml"} {
+		results = append(results, verifyEndpoint(target.Client(), target.URL, extra, dir, filepath.Join(dir, name), nil))
+	}
+
+	assert.False(t, results[0].failed())
+	assert.Equal(t, "Bearer real", authorization)
+	assert.True(t, results[1].failed())
+	assert.Error(t, results[1].Checks[0].Err)
+	assert.Error(t, results[2].Err)
+
+	report := junitReport(results)
+	assert.Equal(t, 3, report.Tests)
+	assert.Equal(t, 1, report.Failures)
+	assert.Equal(t, 1, report.Errors)
+	assert.Nil(t, report.Cases[0].Failure)
+	assert.Contains(t, report.Cases[1].Failure.Text, "status 201: got 400")
+
+	path := filepath.Join(t.TempDir(), "report.xml")
+	assert.NoError(t, writeJUnitReport(path, results))
+	content, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	var parsed junitTestSuite
+	assert.NoError(t, xml.Unmarshal(content, &parsed))
+	assert.Len(t, parsed.Cases, 3)
+}
+
+func TestParseHeaderFlags(t *testing.T) {
+	headers, err := parseHeaderFlags([]string{"Authorization: Bearer x", "X-Env:staging"})
+	assert.NoError(t, err)
+	assert.Equal(t, "Bearer x", headers.Get("Authorization"))
+	assert.Equal(t, "staging", headers.Get("X-Env"))
+
+	_, err = parseHeaderFlags([]string{"no-colon"})
+	assert.Error(t, err)
+}
@@ -2,8 +2,10 @@ package config
 
 import (
 	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
 
 	"github.com/nicobistolfi/mockthis-cli/internal/utils"
 )
@@ -11,10 +13,31 @@ import (
 // ConfigDir is the directory where the config file is stored
 var (
 	BaseURL   = getBaseURL()
-	TokenFile = ".credentials"
+	TokenFile = defaultTokenFile
 	ConfigDir = ".mockthis"
 )
 
+// ProfileEnv is the environment variable naming the profile used when --profile isn't given
+const ProfileEnv = "MOCKTHIS_PROFILE"
+
+const defaultTokenFile = ".credentials"
+
+var profilePattern = regexp.MustCompile(`^[A-Za-z0-9][-A-Za-z0-9_.]*$`)
+
+// UseProfile switches to the credentials of a named profile, each profile being logged in to
+// its own account. The empty name is the default profile.
+func UseProfile(name string) error {
+	if name == "" {
+		TokenFile = defaultTokenFile
+		return nil
+	}
+	if !profilePattern.MatchString(name) {
+		return fmt.Errorf("invalid profile %q: use alphanumeric characters, '-', '_' or '.'", name)
+	}
+	TokenFile = defaultTokenFile + "." + name
+	return nil
+}
+
 func getBaseURL() string {
 	if url := os.Getenv("MOCKTHIS_API"); url != "" {
 		return url
@@ -26,6 +49,8 @@ func getBaseURL() string {
 type Data struct {
 	Token string `json:"token"`
 	Email string `json:"email"`
+	// Project is the ID of the current project, set with `mockthis project use`
+	Project string `json:"project"`
 }
 
 // SaveConfig saves the config to the config file
@@ -80,6 +105,10 @@ func LoadConfig(filename string) (*Data, error) {
 		Token: parsedData["token"].(string),
 		Email: parsedData["email"].(string),
 	}
+	// Configs saved before projects existed have no project
+	if project, ok := parsedData["project"].(string); ok {
+		config.Project = project
+	}
 
 	return config, nil
 }
@@ -26,8 +26,9 @@ func TestSaveAndLoadConfig(t *testing.T) {
 
 	// Test data
 	testConfig := &Data{
-		Token: "test_token",
-		Email: "test@example.com",
+		Token:   "test_token",
+		Email:   "test@example.com",
+		Project: "project_id",
 	}
 
 	// Test SaveConfig
@@ -43,7 +44,26 @@ func TestSaveAndLoadConfig(t *testing.T) {
 	}
 
 	// Compare saved and loaded data
-	if loadedConfig.Token != testConfig.Token || loadedConfig.Email != testConfig.Email {
+	if *loadedConfig != *testConfig {
 		t.Errorf("Loaded config does not match saved config. Got %+v, want %+v", loadedConfig, testConfig)
 	}
 }
+
+func TestUseProfile(t *testing.T) {
+	defer func() { _ = UseProfile("") }()
+
+	if err := UseProfile("production"); err != nil {
+		t.Fatalf("UseProfile failed: %v", err)
+	}
+	if TokenFile != ".credentials.production" {
+		t.Errorf("Expected the production credentials file, got %s", TokenFile)
+	}
+
+	if err := UseProfile("../other"); err == nil {
+		t.Error("Expected an error for a profile name with a path")
+	}
+
+	if err := UseProfile(""); err != nil || TokenFile != ".credentials" {
+		t.Errorf("Expected the default credentials file, got %s (%v)", TokenFile, err)
+	}
+}
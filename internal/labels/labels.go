@@ -0,0 +1,224 @@
+// Package labels parses endpoint labels and the label selectors that filter endpoints.
+package labels
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var (
+	keyPattern   = regexp.MustCompile(`^([A-Za-z0-9]([-A-Za-z0-9_.]*[A-Za-z0-9])?/)?[A-Za-z0-9]([-A-Za-z0-9_.]*[A-Za-z0-9])?$`)
+	valuePattern = regexp.MustCompile(`^([A-Za-z0-9]([-A-Za-z0-9_.]*[A-Za-z0-9])?)?$`)
+)
+
+const maxLength = 63
+
+// ValidateKey checks a label key: alphanumeric characters, '-', '_' and '.', starting and
+// ending with an alphanumeric character, and optionally prefixed with "prefix/"
+func ValidateKey(key string) error {
+	if !keyPattern.MatchString(key) || len(key[strings.LastIndex(key, "/")+1:]) > maxLength {
+		return fmt.Errorf("invalid label key %q: use up to %d alphanumeric characters, '-', '_' or '.', starting and ending with an alphanumeric character", key, maxLength)
+	}
+	return nil
+}
+
+// ValidateValue checks a label value, which follows the rules of keys without a prefix and
+// may be empty
+func ValidateValue(value string) error {
+	if !valuePattern.MatchString(value) || len(value) > maxLength {
+		return fmt.Errorf("invalid label value %q: use up to %d alphanumeric characters, '-', '_' or '.', starting and ending with an alphanumeric character", value, maxLength)
+	}
+	return nil
+}
+
+// Parse parses key=value labels, and the key- removals of labels
+func Parse(pairs []string) (map[string]string, []string, error) {
+	set := make(map[string]string)
+	var remove []string
+
+	for _, pair := range pairs {
+		pair = strings.TrimSpace(pair)
+		key, value, ok := strings.Cut(pair, "=")
+		if !ok {
+			if !strings.HasSuffix(pair, "-") {
+				return nil, nil, fmt.Errorf("%q is not a key=value label", pair)
+			}
+			key = strings.TrimSuffix(pair, "-")
+			if err := ValidateKey(key); err != nil {
+				return nil, nil, err
+			}
+			remove = append(remove, key)
+			continue
+		}
+
+		if err := ValidateKey(key); err != nil {
+			return nil, nil, err
+		}
+		if err := ValidateValue(value); err != nil {
+			return nil, nil, err
+		}
+		set[key] = value
+	}
+	return set, remove, nil
+}
+
+// FromValue returns the labels of a decoded JSON or YAML labels object
+func FromValue(value interface{}) map[string]string {
+	labels := make(map[string]string)
+	object, _ := value.(map[string]interface{})
+	for key, v := range object {
+		labels[key] = fmt.Sprintf("%v", v)
+	}
+	return labels
+}
+
+// Format writes labels as sorted key=value pairs separated by commas
+func Format(labels map[string]string) string {
+	pairs := make([]string, 0, len(labels))
+	for key, value := range labels {
+		pairs = append(pairs, key+"="+value)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+type operator string
+
+const (
+	equals    operator = "="
+	notEquals operator = "!="
+	in        operator = "in"
+	notIn     operator = "notin"
+	exists    operator = "exists"
+	notExists operator = "!"
+)
+
+type requirement struct {
+	key      string
+	operator operator
+	values   []string
+}
+
+// Selector selects labels matching all its requirements
+type Selector []requirement
+
+// ParseSelector parses a comma-separated list of requirements: key=value, key==value,
+// key!=value, key in (a,b), key notin (a,b), key and !key
+func ParseSelector(selector string) (Selector, error) {
+	var parsed Selector
+	for _, expression := range splitRequirements(selector) {
+		expression = strings.TrimSpace(expression)
+		if expression == "" {
+			continue
+		}
+		r, err := parseRequirement(expression)
+		if err != nil {
+			return nil, err
+		}
+		parsed = append(parsed, r)
+	}
+	return parsed, nil
+}
+
+// splitRequirements splits on the commas outside parentheses
+func splitRequirements(selector string) []string {
+	var parts []string
+	depth, start := 0, 0
+	for i, c := range selector {
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				parts = append(parts, selector[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(parts, selector[start:])
+}
+
+func parseRequirement(expression string) (requirement, error) {
+	if key, ok := strings.CutPrefix(expression, "!"); ok {
+		key = strings.TrimSpace(key)
+		return requirement{key: key, operator: notExists}, ValidateKey(key)
+	}
+
+	for _, op := range []operator{notEquals, "==", equals} {
+		if key, value, ok := strings.Cut(expression, string(op)); ok {
+			key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+			if op == "==" {
+				op = equals
+			}
+			if err := ValidateKey(key); err != nil {
+				return requirement{}, err
+			}
+			return requirement{key: key, operator: op, values: []string{value}}, ValidateValue(value)
+		}
+	}
+
+	fields := strings.Fields(expression)
+	if len(fields) == 1 {
+		return requirement{key: fields[0], operator: exists}, ValidateKey(fields[0])
+	}
+	if len(fields) >= 2 && (fields[1] == string(in) || fields[1] == string(notIn)) {
+		key, op := fields[0], operator(fields[1])
+		list := strings.TrimSpace(strings.Join(fields[2:], " "))
+		if !strings.HasPrefix(list, "(") || !strings.HasSuffix(list, ")") {
+			return requirement{}, fmt.Errorf("%q: the values of %s must be in parentheses, as in %s %s (a,b)", expression, op, key, op)
+		}
+		if err := ValidateKey(key); err != nil {
+			return requirement{}, err
+		}
+
+		r := requirement{key: key, operator: op}
+		for _, value := range strings.Split(list[1:len(list)-1], ",") {
+			value = strings.TrimSpace(value)
+			if err := ValidateValue(value); err != nil {
+				return requirement{}, err
+			}
+			r.values = append(r.values, value)
+		}
+		return r, nil
+	}
+	return requirement{}, fmt.Errorf("%q is not a label selector requirement", expression)
+}
+
+// Matches reports whether labels match every requirement of the selector
+func (s Selector) Matches(labels map[string]string) bool {
+	for _, r := range s {
+		value, ok := labels[r.key]
+		switch r.operator {
+		case exists:
+			if !ok {
+				return false
+			}
+		case notExists:
+			if ok {
+				return false
+			}
+		case equals, in:
+			if !ok || !contains(r.values, value) {
+				return false
+			}
+		case notEquals, notIn:
+			if ok && contains(r.values, value) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
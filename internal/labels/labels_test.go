@@ -0,0 +1,53 @@
+package labels
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	set, remove, err := Parse([]string{"team=payments", " env=ci", "example.com/tier=", "old-"})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"team": "payments", "env": "ci", "example.com/tier": ""}, set)
+	assert.Equal(t, []string{"old"}, remove)
+
+	for _, invalid := range []string{"team", "-team=a", "team=a b", "team=-a"} {
+		_, _, err := Parse([]string{invalid})
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestSelector(t *testing.T) {
+	labels := map[string]string{"team": "payments", "env": "ci"}
+
+	tests := map[string]bool{
+		"":                                 true,
+		"team=payments":                    true,
+		"team==payments,env=ci":            true,
+		"team=payments,env=prod":           false,
+		"team!=search":                     true,
+		"owner!=me":                        true,
+		"env in (ci, staging)":             true,
+		"env notin (ci,staging),team":      false,
+		"team,!owner":                      true,
+		"!team":                            false,
+		"owner":                            false,
+		"team in (payments),env notin (x)": true,
+	}
+	for selector, expected := range tests {
+		parsed, err := ParseSelector(selector)
+		if assert.NoError(t, err, selector) {
+			assert.Equal(t, expected, parsed.Matches(labels), selector)
+		}
+	}
+
+	for _, invalid := range []string{"team in payments", "team=a=b", "env ci"} {
+		_, err := ParseSelector(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestFormat(t *testing.T) {
+	assert.Equal(t, "env=ci,team=payments", Format(FromValue(map[string]interface{}{"team": "payments", "env": "ci"})))
+}
@@ -0,0 +1,173 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// jsonPathTemplate is a template like 'id={.id} url={.endpointUrl}' where each {...} is a
+// JSONPath expression. Expressions selecting several values print them separated by spaces.
+type jsonPathTemplate struct {
+	parts []jsonPathPart
+}
+
+// jsonPathPart is either literal text or, when steps is not nil, an expression
+type jsonPathPart struct {
+	text  string
+	steps []jsonPathStep
+}
+
+// jsonPathStep selects a map key, a list index or, with all set, every element
+type jsonPathStep struct {
+	key   string
+	index int
+	isKey bool
+	all   bool
+}
+
+func parseJSONPath(text string) (*jsonPathTemplate, error) {
+	tmpl := &jsonPathTemplate{}
+	if !strings.Contains(text, "{") {
+		// kubectl style shorthand: -o jsonpath=.endpointUrl
+		text = "{" + text + "}"
+	}
+
+	for len(text) > 0 {
+		start := strings.Index(text, "{")
+		if start < 0 {
+			tmpl.parts = append(tmpl.parts, jsonPathPart{text: text})
+			break
+		}
+		if start > 0 {
+			tmpl.parts = append(tmpl.parts, jsonPathPart{text: text[:start]})
+		}
+		end := strings.Index(text[start:], "}")
+		if end < 0 {
+			return nil, fmt.Errorf("unclosed { in %q", text)
+		}
+		expression := strings.TrimSpace(text[start+1 : start+end])
+		steps, err := parseJSONPathExpression(expression)
+		if err != nil {
+			return nil, err
+		}
+		tmpl.parts = append(tmpl.parts, jsonPathPart{steps: steps})
+		text = text[start+end+1:]
+	}
+	return tmpl, nil
+}
+
+func parseJSONPathExpression(expression string) ([]jsonPathStep, error) {
+	expression = strings.TrimPrefix(expression, "$")
+	steps := []jsonPathStep{}
+
+	for len(expression) > 0 {
+		switch expression[0] {
+		case '.':
+			expression = expression[1:]
+			end := strings.IndexAny(expression, ".[")
+			if end < 0 {
+				end = len(expression)
+			}
+			if end == 0 {
+				return nil, fmt.Errorf("empty field name in %q", expression)
+			}
+			steps = append(steps, jsonPathStep{key: expression[:end], isKey: true})
+			expression = expression[end:]
+		case '[':
+			end := strings.Index(expression, "]")
+			if end < 0 {
+				return nil, fmt.Errorf("unclosed [ in %q", expression)
+			}
+			selector := expression[1:end]
+			expression = expression[end+1:]
+
+			switch {
+			case selector == "*":
+				steps = append(steps, jsonPathStep{all: true})
+			case len(selector) >= 2 && (selector[0] == '\'' || selector[0] == '"') && selector[len(selector)-1] == selector[0]:
+				steps = append(steps, jsonPathStep{key: selector[1 : len(selector)-1], isKey: true})
+			default:
+				index, err := strconv.Atoi(selector)
+				if err != nil {
+					return nil, fmt.Errorf("invalid index [%s]", selector)
+				}
+				steps = append(steps, jsonPathStep{index: index})
+			}
+		default:
+			return nil, fmt.Errorf("expected . or [ at %q", expression)
+		}
+	}
+	return steps, nil
+}
+
+func (t *jsonPathTemplate) execute(data interface{}) (string, error) {
+	var b strings.Builder
+	for _, part := range t.parts {
+		if part.steps == nil {
+			b.WriteString(part.text)
+			continue
+		}
+
+		values := []interface{}{data}
+		for _, step := range part.steps {
+			values = step.apply(values)
+		}
+
+		texts := make([]string, 0, len(values))
+		for _, value := range values {
+			text, err := jsonPathText(value)
+			if err != nil {
+				return "", err
+			}
+			texts = append(texts, text)
+		}
+		b.WriteString(strings.Join(texts, " "))
+	}
+	return b.String(), nil
+}
+
+// apply selects the step from each value, dropping values it does not exist in
+func (s jsonPathStep) apply(values []interface{}) []interface{} {
+	selected := []interface{}{}
+	for _, value := range values {
+		switch v := value.(type) {
+		case map[string]interface{}:
+			if s.all {
+				for _, item := range v {
+					selected = append(selected, item)
+				}
+			} else if item, ok := v[s.key]; ok && s.isKey {
+				selected = append(selected, item)
+			}
+		case []interface{}:
+			if s.all {
+				selected = append(selected, v...)
+				continue
+			}
+			index := s.index
+			if index < 0 {
+				index += len(v)
+			}
+			if !s.isKey && index >= 0 && index < len(v) {
+				selected = append(selected, v[index])
+			}
+		}
+	}
+	return selected
+}
+
+// jsonPathText prints strings and numbers as is and other values as JSON
+func jsonPathText(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case float64, int, bool:
+		return fmt.Sprintf("%v", v), nil
+	}
+	encoded, err := json.Marshal(value)
+	return string(encoded), err
+}
@@ -0,0 +1,198 @@
+// Package output renders command results in the formats selected with --output.
+package output
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"text/template"
+
+	"github.com/nicobistolfi/mockthis-cli/internal/utils"
+)
+
+// Formats lists the formats accepted by Print, for flag help
+const Formats = "table, wide, yaml, json, jsonl, csv, name, jsonpath=<template> or go-template=<template>"
+
+// Column is a column of the table, wide and csv formats
+type Column struct {
+	Header string
+	Field  string
+	// Wide columns are only shown by the wide format
+	Wide bool
+	// Format converts the field value to text, fmt's %v is used when nil
+	Format func(value interface{}) string
+}
+
+// Print writes items in the given format. The json and yaml formats always print a list,
+// templates are applied to each item in turn, and the name format prints the field of the
+// first column.
+func Print(w io.Writer, format string, items []map[string]interface{}, columns []Column) error {
+	return printItems(w, format, items, items, columns)
+}
+
+// PrintOne writes the single result of a command, such as the endpoint of get, in the given
+// format. The json and yaml formats print the item itself rather than a list of it.
+func PrintOne(w io.Writer, format string, item map[string]interface{}, columns []Column) error {
+	return printItems(w, format, []map[string]interface{}{item}, item, columns)
+}
+
+// printItems writes items in the given format, data being what the json and yaml formats encode
+func printItems(w io.Writer, format string, items []map[string]interface{}, data interface{}, columns []Column) error {
+	name, argument, _ := strings.Cut(format, "=")
+
+	switch name {
+	case "", "table":
+		return printTable(w, items, visibleColumns(columns, false))
+	case "wide":
+		return printTable(w, items, visibleColumns(columns, true))
+	case "csv":
+		return printCSV(w, items, visibleColumns(columns, true))
+	case "json":
+		encoded, err := json.MarshalIndent(data, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(w, string(encoded))
+		return err
+	case "jsonl":
+		for _, item := range items {
+			encoded, err := json.Marshal(item)
+			if err != nil {
+				return err
+			}
+			if _, err := fmt.Fprintln(w, string(encoded)); err != nil {
+				return err
+			}
+		}
+		return nil
+	case "yaml":
+		encoded, err := utils.ToYAML(data)
+		if err != nil {
+			return err
+		}
+		_, err = io.WriteString(w, encoded)
+		return err
+	case "name":
+		for _, item := range items {
+			if _, err := fmt.Fprintln(w, formatField(item, columns[0])); err != nil {
+				return err
+			}
+		}
+		return nil
+	case "jsonpath":
+		return printJSONPath(w, items, argument)
+	case "go-template":
+		return printTemplate(w, items, argument)
+	default:
+		return fmt.Errorf("unknown output format %q, use one of %s", format, Formats)
+	}
+}
+
+func visibleColumns(columns []Column, wide bool) []Column {
+	visible := make([]Column, 0, len(columns))
+	for _, column := range columns {
+		if wide || !column.Wide {
+			visible = append(visible, column)
+		}
+	}
+	return visible
+}
+
+func formatField(item map[string]interface{}, column Column) string {
+	value, ok := item[column.Field]
+	if column.Format != nil {
+		return column.Format(value)
+	}
+	if !ok || value == nil {
+		return ""
+	}
+	return fmt.Sprintf("%v", value)
+}
+
+func printTable(w io.Writer, items []map[string]interface{}, columns []Column) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	headers := make([]string, len(columns))
+	underlines := make([]string, len(columns))
+	for i, column := range columns {
+		headers[i] = column.Header
+		underlines[i] = strings.Repeat("-", len(column.Header))
+	}
+	fmt.Fprintln(tw, strings.Join(headers, "\t"))
+	fmt.Fprintln(tw, strings.Join(underlines, "\t"))
+
+	for _, item := range items {
+		values := make([]string, len(columns))
+		for i, column := range columns {
+			values[i] = formatField(item, column)
+		}
+		fmt.Fprintln(tw, strings.Join(values, "\t"))
+	}
+
+	return tw.Flush()
+}
+
+func printCSV(w io.Writer, items []map[string]interface{}, columns []Column) error {
+	cw := csv.NewWriter(w)
+
+	headers := make([]string, len(columns))
+	for i, column := range columns {
+		headers[i] = column.Header
+	}
+	if err := cw.Write(headers); err != nil {
+		return err
+	}
+
+	for _, item := range items {
+		values := make([]string, len(columns))
+		for i, column := range columns {
+			values[i] = formatField(item, column)
+		}
+		if err := cw.Write(values); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+func printTemplate(w io.Writer, items []map[string]interface{}, text string) error {
+	tmpl, err := template.New("output").Option("missingkey=zero").Parse(text)
+	if err != nil {
+		return fmt.Errorf("invalid go-template: %v", err)
+	}
+
+	for _, item := range items {
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, item); err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintln(w, buf.String()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func printJSONPath(w io.Writer, items []map[string]interface{}, text string) error {
+	tmpl, err := parseJSONPath(text)
+	if err != nil {
+		return fmt.Errorf("invalid jsonpath: %v", err)
+	}
+
+	for _, item := range items {
+		result, err := tmpl.execute(item)
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintln(w, result); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package output
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testItems = []map[string]interface{}{
+	{
+		"id":          "a1",
+		"status":      float64(200),
+		"endpointUrl": "https://mockthis.io/m/a1",
+		"httpHeaders": map[string]interface{}{"X-Trace": "on"},
+		"tags":        []interface{}{"users", "v1"},
+	},
+	{
+		"id":          "b2",
+		"status":      float64(404),
+		"endpointUrl": "https://mockthis.io/m/b2",
+	},
+}
+
+var testColumns = []Column{
+	{Header: "ID", Field: "id"},
+	{Header: "Status", Field: "status"},
+	{Header: "URL", Field: "endpointUrl", Wide: true},
+}
+
+func TestPrint(t *testing.T) {
+	tests := []struct {
+		name     string
+		format   string
+		items    []map[string]interface{}
+		expected string
+	}{
+		{"table", "table", testItems, "ID  Status\n--  ------\na1  200\nb2  404\n"},
+		{"wide", "wide", testItems[1:], "ID  Status  URL\n--  ------  ---\nb2  404     https://mockthis.io/m/b2\n"},
+		{"csv", "csv", testItems, "ID,Status,URL\na1,200,https://mockthis.io/m/a1\nb2,404,https://mockthis.io/m/b2\n"},
+		{"name", "name", testItems, "a1\nb2\n"},
+		{"jsonl", "jsonl", testItems[1:], `{"endpointUrl":"https://mockthis.io/m/b2","id":"b2","status":404}` + "\n"},
+		{"json single item", "json", testItems[1:], "[\n  {\n    \"endpointUrl\": \"https://mockthis.io/m/b2\",\n    \"id\": \"b2\",\n    \"status\": 404\n  }\n]\n"},
+		{"json no items", "json", []map[string]interface{}{}, "[]\n"},
+		{"yaml", "yaml", testItems[1:], "- endpointUrl: https://mockthis.io/m/b2\n  id: b2\n  status: 404\n"},
+		{"jsonpath", "jsonpath={.endpointUrl}", testItems, "https://mockthis.io/m/a1\nhttps://mockthis.io/m/b2\n"},
+		{"jsonpath shorthand", "jsonpath=.id", testItems, "a1\nb2\n"},
+		{"jsonpath with text", "jsonpath=id={.id} status={.status}", testItems[:1], "id=a1 status=200\n"},
+		{"jsonpath nested", "jsonpath={.httpHeaders['X-Trace']} {.tags[*]} {.tags[-1]}", testItems[:1], "on users v1 v1\n"},
+		{"jsonpath missing", "jsonpath={.httpHeaders.X-Trace}", testItems[1:], "\n"},
+		{"go-template", "go-template={{.id}} {{.endpointUrl}}", testItems, "a1 https://mockthis.io/m/a1\nb2 https://mockthis.io/m/b2\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			err := Print(&buf, tt.format, tt.items, testColumns)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, buf.String())
+		})
+	}
+}
+
+func TestPrintOne(t *testing.T) {
+	tests := []struct {
+		name     string
+		format   string
+		expected string
+	}{
+		{"table", "table", "ID  Status\n--  ------\nb2  404\n"},
+		{"json", "json", "{\n  \"endpointUrl\": \"https://mockthis.io/m/b2\",\n  \"id\": \"b2\",\n  \"status\": 404\n}\n"},
+		{"yaml", "yaml", "endpointUrl: https://mockthis.io/m/b2\nid: b2\nstatus: 404\n"},
+		{"jsonpath", "jsonpath={.endpointUrl}", "https://mockthis.io/m/b2\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			err := PrintOne(&buf, tt.format, testItems[1], testColumns)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, buf.String())
+		})
+	}
+}
+
+func TestPrintErrors(t *testing.T) {
+	for _, format := range []string{"xml", "jsonpath={.id", "jsonpath={id}", "jsonpath={.tags[x]}", "go-template={{.id"} {
+		t.Run(format, func(t *testing.T) {
+			err := Print(&bytes.Buffer{}, format, testItems, testColumns)
+			assert.Error(t, err)
+		})
+	}
+}
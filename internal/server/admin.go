@@ -0,0 +1,188 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/nicobistolfi/mockthis-cli/internal/utils"
+)
+
+// EndpointSummary describes a served endpoint in admin API responses
+type EndpointSummary struct {
+	Name     string `json:"name"`
+	Method   string `json:"method"`
+	Path     string `json:"path"`
+	Query    string `json:"query,omitempty"`
+	Status   int    `json:"status"`
+	Scenario string `json:"scenario,omitempty"`
+}
+
+func (s *Server) adminRoutes() *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET "+AdminPrefix+"/endpoints", s.listEndpoints)
+	mux.HandleFunc("PUT "+AdminPrefix+"/endpoints/{name...}", s.putEndpoint)
+	mux.HandleFunc("DELETE "+AdminPrefix+"/endpoints/{name...}", s.deleteEndpoint)
+	mux.HandleFunc("GET "+AdminPrefix+"/requests", s.listRequests)
+	mux.HandleFunc("DELETE "+AdminPrefix+"/requests", s.clearRequests)
+	mux.HandleFunc("POST "+AdminPrefix+"/requests/count", s.countRequests)
+	mux.HandleFunc("POST "+AdminPrefix+"/reset", s.reset)
+	mux.HandleFunc("GET "+AdminPrefix+"/scenarios", s.listScenarios)
+	mux.HandleFunc("PUT "+AdminPrefix+"/scenarios/{name}/state", s.setScenarioState)
+	mux.HandleFunc("POST "+AdminPrefix+"/scenarios/reset", s.resetScenarios)
+	mux.HandleFunc("POST "+AdminPrefix+"/scenarios/{name}/reset", s.resetScenario)
+	return mux
+}
+
+func (s *Server) listEndpoints(w http.ResponseWriter, r *http.Request) {
+	summaries := []EndpointSummary{}
+	for _, endpoint := range s.Endpoints() {
+		summary := EndpointSummary{
+			Name:   endpoint.Name,
+			Method: endpoint.Method,
+			Path:   endpoint.Path,
+			Query:  endpoint.Query.Encode(),
+			Status: endpoint.Response.Status,
+		}
+		if endpoint.Scenario != nil {
+			summary.Scenario = endpoint.Scenario.Name
+		}
+		summaries = append(summaries, summary)
+	}
+	writeJSON(w, http.StatusOK, summaries)
+}
+
+// putEndpoint adds or replaces an endpoint from a JSON or YAML endpoint file in the request body
+func (s *Server) putEndpoint(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	endpoint, err := s.parseEndpoint(name, string(body))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := s.AddEndpoint(endpoint); err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) parseEndpoint(name, content string) (*Endpoint, error) {
+	var data map[string]interface{}
+	var err error
+	switch {
+	case utils.IsJSON(content):
+		data, err = utils.ParseJSON(content)
+	case utils.IsYAML(content):
+		data, err = utils.ParseYAML(content)
+	default:
+		return nil, fmt.Errorf("request body is not a JSON or YAML endpoint file")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	validate := s.validate
+	s.mu.RUnlock()
+	if validate != nil {
+		if err := validate(data); err != nil {
+			return nil, err
+		}
+	}
+
+	endpointData, ok := data["endpoint"].(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("endpoint data is not a map or the endpoint key is not found")
+	}
+	endpoint, err := NewEndpoint(name, endpointData)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkEndpoints([]*Endpoint{endpoint}); err != nil {
+		return nil, err
+	}
+	return endpoint, nil
+}
+
+func (s *Server) deleteEndpoint(w http.ResponseWriter, r *http.Request) {
+	if !s.RemoveEndpoint(r.PathValue("name")) {
+		http.Error(w, "Endpoint not found", http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// listRequests returns the journal, filtered by the method, path, body and bodyContains query parameters
+func (s *Server) listRequests(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	pattern := RequestPattern{
+		Method:       query.Get("method"),
+		Path:         query.Get("path"),
+		Body:         query.Get("body"),
+		BodyContains: query.Get("bodyContains"),
+	}
+	writeJSON(w, http.StatusOK, s.journal.find(pattern))
+}
+
+func (s *Server) clearRequests(w http.ResponseWriter, r *http.Request) {
+	s.journal.clear()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// countRequests answers how many journal entries match the request pattern in the body
+func (s *Server) countRequests(w http.ResponseWriter, r *http.Request) {
+	var pattern RequestPattern
+	if err := json.NewDecoder(r.Body).Decode(&pattern); err != nil && err != io.EOF {
+		http.Error(w, "Request body must be a request pattern", http.StatusBadRequest)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]int{"count": len(s.journal.find(pattern))})
+}
+
+func (s *Server) reset(w http.ResponseWriter, r *http.Request) {
+	s.Reset()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) listScenarios(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.scenarios.list(s.scenarioNames()))
+}
+
+func (s *Server) setScenarioState(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		State string `json:"state"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.State == "" {
+		http.Error(w, `Request body must be {"state": "<state>"}`, http.StatusBadRequest)
+		return
+	}
+
+	s.scenarios.set(r.PathValue("name"), body.State)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) resetScenarios(w http.ResponseWriter, r *http.Request) {
+	s.scenarios.resetAll()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) resetScenario(w http.ResponseWriter, r *http.Request) {
+	s.scenarios.reset(r.PathValue("name"))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
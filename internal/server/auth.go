@@ -0,0 +1,82 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// authorized checks the request against the credentials of an endpoint file auth block
+func authorized(r *http.Request, auth map[string]interface{}) bool {
+	if auth == nil {
+		return true
+	}
+
+	switch authType, _ := auth["type"].(string); authType {
+	case "basic":
+		username, password, ok := r.BasicAuth()
+		return ok && username == authProperty(auth, "username") && password == authProperty(auth, "password")
+	case "apiKey":
+		name := authProperty(auth, "name")
+		if authProperty(auth, "in") == "query" {
+			return r.URL.Query().Get(name) == authProperty(auth, "value")
+		}
+		return r.Header.Get(name) == authProperty(auth, "value")
+	case "bearer", "jwt":
+		return hasAuthorization(r, "Bearer", authProperty(auth, "token"))
+	case "oauth2":
+		tokenType := authProperty(auth, "tokenType")
+		if tokenType == "" {
+			tokenType = "Bearer"
+		}
+		return hasAuthorization(r, tokenType, authProperty(auth, "accessToken"))
+	case "mtls":
+		return hasClientCertificate(r, authProperty(auth, "subject"), authProperty(auth, "san"))
+	default:
+		return false
+	}
+}
+
+// authProperty reads a credential from the nested "properties" map or from the auth block itself
+func authProperty(auth map[string]interface{}, key string) string {
+	if properties, ok := auth["properties"].(map[string]interface{}); ok {
+		if value, ok := properties[key]; ok && value != nil {
+			return fmt.Sprintf("%v", value)
+		}
+	}
+	if value, ok := auth[key]; ok && value != nil {
+		return fmt.Sprintf("%v", value)
+	}
+	return ""
+}
+
+func hasAuthorization(r *http.Request, scheme, credentials string) bool {
+	got, value, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	return ok && strings.EqualFold(got, scheme) && value == credentials
+}
+
+// hasClientCertificate checks the verified client certificate of the request against the
+// expected subject (full distinguished name or common name) and subject alternative name
+func hasClientCertificate(r *http.Request, subject, san string) bool {
+	if r.TLS == nil || len(r.TLS.PeerCertificates) == 0 {
+		return false
+	}
+	cert := r.TLS.PeerCertificates[0]
+
+	if subject != "" && subject != cert.Subject.String() && subject != cert.Subject.CommonName {
+		return false
+	}
+	if san == "" {
+		return true
+	}
+
+	names := append(append([]string{}, cert.DNSNames...), cert.EmailAddresses...)
+	for _, ip := range cert.IPAddresses {
+		names = append(names, ip.String())
+	}
+	for _, uri := range cert.URIs {
+		names = append(names, uri.String())
+	}
+	return slices.Contains(names, san)
+}
@@ -0,0 +1,132 @@
+package server
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// CORS is the cross-origin resource sharing policy of an endpoint or of the whole server
+type CORS struct {
+	Origins     []string
+	Methods     []string
+	Headers     []string
+	Credentials bool
+	MaxAge      int
+}
+
+// NewCORS builds a policy from the "cors" block of an endpoint file
+func NewCORS(data map[string]interface{}) *CORS {
+	cors := &CORS{
+		Origins: stringList(data["origins"]),
+		Methods: stringList(data["methods"]),
+		Headers: stringList(data["headers"]),
+	}
+	cors.Credentials, _ = data["credentials"].(bool)
+	if maxAge, err := strconv.Atoi(fmt.Sprintf("%v", data["max-age"])); err == nil {
+		cors.MaxAge = maxAge
+	}
+	return cors
+}
+
+// SetCORS sets the policy used by endpoints without a cors block and by unmatched preflight requests
+func (s *Server) SetCORS(cors *CORS) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cors = cors
+}
+
+func (s *Server) corsFor(endpoint *Endpoint) *CORS {
+	if endpoint != nil && endpoint.CORS != nil {
+		return endpoint.CORS
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.cors
+}
+
+func isPreflight(r *http.Request) bool {
+	return r.Method == http.MethodOptions && r.Header.Get("Origin") != "" && r.Header.Get("Access-Control-Request-Method") != ""
+}
+
+// preflight answers a CORS preflight request for the endpoint it is about. It returns
+// false when neither the endpoint nor the server has a policy.
+func (s *Server) preflight(w http.ResponseWriter, r *http.Request) bool {
+	requested := r.Clone(r.Context())
+	requested.Method = strings.ToUpper(r.Header.Get("Access-Control-Request-Method"))
+	endpoint := s.match(requested)
+
+	cors := s.corsFor(endpoint)
+	if cors == nil {
+		return false
+	}
+
+	name := "server CORS policy"
+	if endpoint != nil {
+		name = endpoint.Name
+	}
+
+	if !cors.allowOrigin(w, r) {
+		log.Printf("%s %s -> %d preflight from %s rejected by %s", r.Method, r.URL.RequestURI(), http.StatusForbidden, r.Header.Get("Origin"), name)
+		w.WriteHeader(http.StatusForbidden)
+		return true
+	}
+
+	methods := cors.Methods
+	if len(methods) == 0 {
+		methods = []string{requested.Method}
+	}
+	w.Header().Set("Access-Control-Allow-Methods", strings.Join(methods, ", "))
+
+	if len(cors.Headers) > 0 {
+		w.Header().Set("Access-Control-Allow-Headers", strings.Join(cors.Headers, ", "))
+	} else if headers := r.Header.Get("Access-Control-Request-Headers"); headers != "" {
+		w.Header().Set("Access-Control-Allow-Headers", headers)
+	}
+	if cors.MaxAge > 0 {
+		w.Header().Set("Access-Control-Max-Age", strconv.Itoa(cors.MaxAge))
+	}
+
+	log.Printf("%s %s -> %d preflight answered by %s", r.Method, r.URL.RequestURI(), http.StatusNoContent, name)
+	w.WriteHeader(http.StatusNoContent)
+	return true
+}
+
+// allowOrigin sets the headers allowing the origin of the request and reports whether it is allowed
+func (c *CORS) allowOrigin(w http.ResponseWriter, r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	w.Header().Add("Vary", "Origin")
+
+	wildcard := len(c.Origins) == 0 || slices.Contains(c.Origins, "*")
+	if !wildcard && !slices.Contains(c.Origins, origin) {
+		return false
+	}
+
+	// Browsers reject a wildcard origin on credentialed requests, so the origin is echoed
+	if wildcard && !c.Credentials {
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+	} else {
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+	}
+	if c.Credentials {
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+	}
+	return true
+}
+
+func stringList(value interface{}) []string {
+	switch v := value.(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		list := make([]string, 0, len(v))
+		for _, item := range v {
+			list = append(list, fmt.Sprintf("%v", item))
+		}
+		return list
+	}
+	return nil
+}
@@ -0,0 +1,283 @@
+package server
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Endpoint is a mock endpoint served by the local server
+type Endpoint struct {
+	Name     string
+	Method   string
+	Path     string
+	Query    url.Values
+	Auth     map[string]interface{}
+	CORS     *CORS
+	Response Response
+	Scenario *Scenario
+
+	pattern pathPattern
+}
+
+// Response is the response returned by an endpoint
+type Response struct {
+	Status      int
+	ContentType string
+	Charset     string
+	Headers     map[string]string
+	Body        []byte
+}
+
+// Scenario ties the responses of an endpoint to the state of a named scenario
+type Scenario struct {
+	Name      string
+	Responses []ScenarioResponse
+}
+
+// ScenarioResponse is returned while the scenario is in State and moves it to Next
+type ScenarioResponse struct {
+	State    string
+	Next     string
+	Response Response
+}
+
+// NewEndpoint builds an endpoint from the "endpoint" block of a validated endpoint file.
+// The endpoint is served on its "path" key, or on /<name> when the file has none. The path
+// may hold {name} parameters and * wildcards matching any segment, and end in ** to match
+// any remaining segments. A query string in the path must be present in the request for
+// the endpoint to match, a * value matching any value.
+func NewEndpoint(name string, data map[string]interface{}) (*Endpoint, error) {
+	responseData, _ := data["response"].(map[string]interface{})
+
+	endpoint := &Endpoint{
+		Name:   name,
+		Method: http.MethodGet,
+		Path:   "/" + strings.TrimPrefix(name, "/"),
+	}
+
+	if method, ok := responseData["method"].(string); ok && method != "" {
+		endpoint.Method = strings.ToUpper(method)
+	}
+	if path, ok := data["path"].(string); ok && path != "" {
+		endpoint.Path = path
+	}
+	pattern, query, err := parsePath(endpoint.Path)
+	if err != nil {
+		return nil, fmt.Errorf("endpoint %s: %v", name, err)
+	}
+	endpoint.Path, _, _ = strings.Cut(endpoint.Path, "?")
+	endpoint.pattern = pattern
+	if len(query) > 0 {
+		endpoint.Query = query
+	}
+	if auth, ok := data["auth"].(map[string]interface{}); ok {
+		endpoint.Auth = auth
+	}
+	if cors, ok := data["cors"].(map[string]interface{}); ok {
+		endpoint.CORS = NewCORS(cors)
+	}
+
+	response, err := newResponse(responseData)
+	if err != nil {
+		return nil, fmt.Errorf("endpoint %s: %v", name, err)
+	}
+	endpoint.Response = response
+
+	if scenarioData, ok := data["scenario"].(map[string]interface{}); ok {
+		scenario, err := newScenario(scenarioData, responseData)
+		if err != nil {
+			return nil, fmt.Errorf("endpoint %s: %v", name, err)
+		}
+		endpoint.Scenario = scenario
+	}
+
+	return endpoint, nil
+}
+
+func (e *Endpoint) matches(r *http.Request) bool {
+	if e.Method != r.Method || !e.pattern.match(r.URL.Path) {
+		return false
+	}
+
+	query := r.URL.Query()
+	for key, values := range e.Query {
+		if len(values) == 1 && values[0] == "*" {
+			if _, ok := query[key]; !ok {
+				return false
+			}
+			continue
+		}
+		if strings.Join(query[key], ",") != strings.Join(values, ",") {
+			return false
+		}
+	}
+	return true
+}
+
+// moreSpecific reports whether the endpoint should answer the requests both it and other
+// match: the one with the most specific path, then the one with the most specific query
+func (e *Endpoint) moreSpecific(other *Endpoint) bool {
+	if diff := e.pattern.compare(other.pattern); diff != 0 {
+		return diff > 0
+	}
+	return len(e.Query) > len(other.Query)
+}
+
+// ExamplePath returns a path and query the endpoint answers, for clients to call it
+func (e *Endpoint) ExamplePath() string {
+	path := e.pattern.example()
+	if len(e.Query) == 0 {
+		return path
+	}
+	query := url.Values{}
+	for key, values := range e.Query {
+		if len(values) == 1 && values[0] == "*" {
+			values = []string{"1"}
+		}
+		query[key] = values
+	}
+	return path + "?" + query.Encode()
+}
+
+// Route identifies the requests an endpoint answers. Parameters are named * in it, as the
+// name of a parameter doesn't change the requests it matches.
+func (e *Endpoint) Route() string {
+	if len(e.Query) > 0 {
+		return e.Method + " " + e.pattern.key() + "?" + e.Query.Encode()
+	}
+	return e.Method + " " + e.pattern.key()
+}
+
+func newScenario(data map[string]interface{}, baseResponse map[string]interface{}) (*Scenario, error) {
+	scenario := &Scenario{}
+	scenario.Name, _ = data["name"].(string)
+
+	responses, _ := data["responses"].([]interface{})
+	for _, item := range responses {
+		entry, ok := item.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("scenario %s: responses must be objects", scenario.Name)
+		}
+
+		// Fields missing from a scenario response are inherited from the endpoint response
+		merged := make(map[string]interface{}, len(baseResponse))
+		for key, value := range baseResponse {
+			merged[key] = value
+		}
+		if override, ok := entry["response"].(map[string]interface{}); ok {
+			if hasBody(override) {
+				for _, key := range bodyKeys {
+					delete(merged, key)
+				}
+			}
+			for key, value := range override {
+				merged[key] = value
+			}
+		}
+
+		response, err := newResponse(merged)
+		if err != nil {
+			return nil, fmt.Errorf("scenario %s: %v", scenario.Name, err)
+		}
+
+		state, _ := entry["state"].(string)
+		next, _ := entry["next"].(string)
+		scenario.Responses = append(scenario.Responses, ScenarioResponse{
+			State:    state,
+			Next:     next,
+			Response: response,
+		})
+	}
+
+	return scenario, nil
+}
+
+// bodyKeys are the keys a response body can be given with
+var bodyKeys = []string{"body", "body_base64", "body_file"}
+
+func hasBody(data map[string]interface{}) bool {
+	for _, key := range bodyKeys {
+		if _, ok := data[key]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+func newResponse(data map[string]interface{}) (Response, error) {
+	response := Response{
+		Status:      http.StatusOK,
+		ContentType: "application/json",
+		Charset:     "UTF-8",
+		Headers:     make(map[string]string),
+	}
+
+	if status, ok := data["status"]; ok {
+		converted, err := strconv.Atoi(fmt.Sprintf("%v", status))
+		if err != nil {
+			return response, fmt.Errorf("invalid status %v", status)
+		}
+		response.Status = converted
+	}
+	if contentType, ok := data["content-type"].(string); ok {
+		response.ContentType = contentType
+	}
+	if charset, ok := data["charset"].(string); ok {
+		response.Charset = charset
+	}
+	if headers, ok := data["headers"].(map[string]interface{}); ok {
+		for key, value := range headers {
+			response.Headers[key] = fmt.Sprintf("%v", value)
+		}
+	}
+
+	if encoded, ok := data["body_base64"].(string); ok {
+		decoded, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(encoded), ""))
+		if err != nil {
+			return response, fmt.Errorf("invalid body_base64: %v", err)
+		}
+		response.Body = decoded
+		return response, nil
+	}
+
+	switch body := data["body"].(type) {
+	case nil:
+	case string:
+		response.Body = []byte(body)
+	default:
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			return response, fmt.Errorf("invalid body: %v", err)
+		}
+		response.Body = encoded
+	}
+
+	return response, nil
+}
+
+func (r Response) write(w http.ResponseWriter) {
+	for key, value := range r.Headers {
+		w.Header().Set(key, value)
+	}
+	if w.Header().Get("Content-Type") == "" && r.ContentType != "" {
+		contentType := r.ContentType
+		if r.Charset != "" {
+			contentType += "; charset=" + r.Charset
+		}
+		w.Header().Set("Content-Type", contentType)
+	}
+	if bodyAllowed(r.Status) {
+		w.Header().Set("Content-Length", strconv.Itoa(len(r.Body)))
+	}
+	w.WriteHeader(r.Status)
+	_, _ = w.Write(r.Body)
+}
+
+func bodyAllowed(status int) bool {
+	return status >= http.StatusOK && status != http.StatusNoContent && status != http.StatusNotModified
+}
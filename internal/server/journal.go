@@ -0,0 +1,181 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// maxJournalEntries is the number of requests kept before the oldest are dropped
+	maxJournalEntries = 10000
+	// maxJournalBody is the number of request body bytes kept for each journal entry
+	maxJournalBody = 1 << 20
+	// maxJournalBytes is the number of request body bytes kept for all the journal entries,
+	// the oldest entries being dropped to stay under it
+	maxJournalBytes = 64 << 20
+)
+
+// JournalEntry is a request received by the server
+type JournalEntry struct {
+	Time     time.Time   `json:"time"`
+	Method   string      `json:"method"`
+	Path     string      `json:"path"`
+	Query    string      `json:"query,omitempty"`
+	Headers  http.Header `json:"headers"`
+	Body     string      `json:"body,omitempty"`
+	Endpoint string      `json:"endpoint,omitempty"`
+	Proxied  bool        `json:"proxied,omitempty"`
+
+	// body is the part of the body read so far, Body being set from it when the entry is
+	// copied out of the journal
+	body []byte
+}
+
+// RequestPattern selects journal entries. Empty fields match everything.
+type RequestPattern struct {
+	Method       string `json:"method,omitempty"`
+	Path         string `json:"path,omitempty"`
+	Body         string `json:"body,omitempty"`
+	BodyContains string `json:"bodyContains,omitempty"`
+}
+
+type journal struct {
+	mu      sync.Mutex
+	entries []*JournalEntry
+	// size is the number of body bytes kept for all the entries
+	size int
+}
+
+// record adds the request to the journal. The body is not read here, for proxied requests
+// to be streamed: the first maxJournalBody bytes are copied to the entry as the body is
+// read, and capture reads them for requests nothing else reads.
+func (j *journal) record(r *http.Request) *JournalEntry {
+	entry := &JournalEntry{
+		Time:    time.Now(),
+		Method:  r.Method,
+		Path:    r.URL.Path,
+		Query:   r.URL.RawQuery,
+		Headers: r.Header.Clone(),
+	}
+
+	if r.Body != nil {
+		r.Body = &journalBody{ReadCloser: r.Body, journal: j, entry: entry}
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if len(j.entries) >= maxJournalEntries {
+		j.drop()
+	}
+	j.entries = append(j.entries, entry)
+	return entry
+}
+
+// capture reads the body of a request nothing else reads, up to what the journal keeps
+func (j *journal) capture(r *http.Request) {
+	if body, ok := r.Body.(*journalBody); ok {
+		_, _ = io.Copy(io.Discard, io.LimitReader(body, maxJournalBody))
+	}
+}
+
+// keep appends read body bytes to an entry, dropping the oldest entries when the journal
+// holds too many body bytes
+func (j *journal) keep(entry *JournalEntry, p []byte) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if room := maxJournalBody - len(entry.body); len(p) > room {
+		p = p[:room]
+	}
+	if len(p) == 0 {
+		return
+	}
+	entry.body = append(entry.body, p...)
+	j.size += len(p)
+	for j.size > maxJournalBytes && len(j.entries) > 0 && j.entries[0] != entry {
+		j.drop()
+	}
+}
+
+// drop removes the oldest entry
+func (j *journal) drop() {
+	j.size -= len(j.entries[0].body)
+	j.entries[0] = nil
+	j.entries = j.entries[1:]
+}
+
+// journalBody is a request body copying what is read of it to its journal entry
+type journalBody struct {
+	io.ReadCloser
+	journal *journal
+	entry   *JournalEntry
+}
+
+func (b *journalBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if n > 0 {
+		b.journal.keep(b.entry, p[:n])
+	}
+	return n, err
+}
+
+// annotate sets how the request of entry was answered
+func (j *journal) annotate(entry *JournalEntry, endpoint string, proxied bool) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	entry.Endpoint = endpoint
+	entry.Proxied = proxied
+}
+
+func (j *journal) find(pattern RequestPattern) []JournalEntry {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	matched := []JournalEntry{}
+	for _, entry := range j.entries {
+		copied := *entry
+		copied.Body, copied.body = string(entry.body), nil
+		if pattern.matches(copied) {
+			matched = append(matched, copied)
+		}
+	}
+	return matched
+}
+
+func (j *journal) clear() {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.entries = nil
+	j.size = 0
+}
+
+func (p RequestPattern) matches(entry JournalEntry) bool {
+	if p.Method != "" && !strings.EqualFold(p.Method, entry.Method) {
+		return false
+	}
+	if p.Path != "" && p.Path != entry.Path {
+		return false
+	}
+	if p.Body != "" && !sameBody(p.Body, entry.Body) {
+		return false
+	}
+	if p.BodyContains != "" && !strings.Contains(entry.Body, p.BodyContains) {
+		return false
+	}
+	return true
+}
+
+// sameBody compares bodies as JSON when both are JSON, and as text otherwise
+func sameBody(expected, actual string) bool {
+	var expectedJSON, actualJSON interface{}
+	if json.Unmarshal([]byte(expected), &expectedJSON) == nil && json.Unmarshal([]byte(actual), &actualJSON) == nil {
+		expectedNormalized, _ := json.Marshal(expectedJSON)
+		actualNormalized, _ := json.Marshal(actualJSON)
+		return bytes.Equal(expectedNormalized, actualNormalized)
+	}
+	return expected == actual
+}
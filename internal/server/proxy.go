@@ -0,0 +1,62 @@
+package server
+
+import (
+	"log"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+)
+
+// SetUpstream makes the server forward requests that match no endpoint to target
+func (s *Server) SetUpstream(target *url.URL) {
+	proxy := httputil.NewSingleHostReverseProxy(target)
+
+	director := proxy.Director
+	proxy.Director = func(r *http.Request) {
+		director(r)
+		r.Host = target.Host
+	}
+	// Flush as soon as the upstream writes so streamed responses are passed through
+	proxy.FlushInterval = -1
+	proxy.ErrorHandler = func(w http.ResponseWriter, r *http.Request, err error) {
+		log.Printf("Error proxying %s %s: %v", r.Method, r.URL.RequestURI(), err)
+		w.WriteHeader(http.StatusBadGateway)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.upstream = proxy
+	s.upstreamURL = target
+}
+
+func (s *Server) proxy(w http.ResponseWriter, r *http.Request) bool {
+	s.mu.RLock()
+	upstream, upstreamURL := s.upstream, s.upstreamURL
+	s.mu.RUnlock()
+
+	if upstream == nil {
+		return false
+	}
+
+	recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+	uri := r.URL.RequestURI()
+	upstream.ServeHTTP(recorder, r)
+	log.Printf("%s %s -> %d proxied to %s", r.Method, uri, recorder.status, upstreamURL)
+	return true
+}
+
+// statusRecorder remembers the status code written through it
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer to flush streamed responses
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
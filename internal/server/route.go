@@ -0,0 +1,134 @@
+package server
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// pathPattern is the path of an endpoint. A {name} segment matches any single segment, as
+// does a * segment, and a ** last segment matches any number of remaining segments.
+type pathPattern struct {
+	segments []string
+}
+
+// Weights of the kinds of segments, to prefer the most specific endpoint for a request
+const (
+	restSegment = iota
+	wildcardSegment
+	literalSegment
+)
+
+// ValidatePath checks the path of an endpoint, with its query
+func ValidatePath(path string) error {
+	_, _, err := parsePath(path)
+	return err
+}
+
+func parsePath(path string) (pathPattern, url.Values, error) {
+	path, rawQuery, _ := strings.Cut(path, "?")
+	query, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return pathPattern{}, nil, fmt.Errorf("invalid query: %v", err)
+	}
+	pattern, err := parsePathPattern(path)
+	return pattern, query, err
+}
+
+func parsePathPattern(path string) (pathPattern, error) {
+	if !strings.HasPrefix(path, "/") {
+		return pathPattern{}, fmt.Errorf("path %s must start with /", path)
+	}
+
+	segments := strings.Split(path[1:], "/")
+	for i, segment := range segments {
+		switch {
+		case segment == "**":
+			if i != len(segments)-1 {
+				return pathPattern{}, fmt.Errorf("path %s: ** must be the last segment", path)
+			}
+		case strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}"):
+			if name := segment[1 : len(segment)-1]; name == "" || strings.ContainsAny(name, "{}") {
+				return pathPattern{}, fmt.Errorf("path %s: %s is not a {name} parameter", path, segment)
+			}
+		case strings.ContainsAny(segment, "{}"):
+			return pathPattern{}, fmt.Errorf("path %s: parameters must be whole segments, as in /users/{id}", path)
+		case strings.Contains(segment, "*") && segment != "*":
+			return pathPattern{}, fmt.Errorf("path %s: wildcards must be whole segments, as in /files/*", path)
+		}
+	}
+	return pathPattern{segments: segments}, nil
+}
+
+func segmentWeight(segment string) int {
+	switch {
+	case segment == "**":
+		return restSegment
+	case segment == "*" || strings.HasPrefix(segment, "{"):
+		return wildcardSegment
+	}
+	return literalSegment
+}
+
+// match reports whether a request path matches the pattern
+func (p pathPattern) match(path string) bool {
+	if !strings.HasPrefix(path, "/") {
+		return false
+	}
+	segments := strings.Split(path[1:], "/")
+
+	for i, segment := range p.segments {
+		switch segmentWeight(segment) {
+		case restSegment:
+			return true
+		case wildcardSegment:
+			if i >= len(segments) || segments[i] == "" {
+				return false
+			}
+		default:
+			if i >= len(segments) || segments[i] != segment {
+				return false
+			}
+		}
+	}
+	return len(segments) == len(p.segments)
+}
+
+// compare orders patterns by specificity: literal segments come before parameters and
+// wildcards, which come before **
+func (p pathPattern) compare(other pathPattern) int {
+	for i := 0; i < len(p.segments) && i < len(other.segments); i++ {
+		if diff := segmentWeight(p.segments[i]) - segmentWeight(other.segments[i]); diff != 0 {
+			return diff
+		}
+	}
+	// Patterns sharing their segments both match a path only when the longer one ends in **
+	return len(other.segments) - len(p.segments)
+}
+
+// key identifies the paths the pattern matches, whatever its parameters are named
+func (p pathPattern) key() string {
+	segments := make([]string, len(p.segments))
+	for i, segment := range p.segments {
+		if segmentWeight(segment) == wildcardSegment {
+			segment = "*"
+		}
+		segments[i] = segment
+	}
+	return "/" + strings.Join(segments, "/")
+}
+
+// example returns a path the pattern matches, with 1 for every parameter and wildcard
+func (p pathPattern) example() string {
+	var segments []string
+	for _, segment := range p.segments {
+		switch segmentWeight(segment) {
+		case restSegment:
+			continue
+		case wildcardSegment:
+			segment = "1"
+		}
+		segments = append(segments, segment)
+	}
+	return "/" + strings.Join(segments, "/")
+}
@@ -0,0 +1,91 @@
+package server
+
+import (
+	"sort"
+	"sync"
+)
+
+// StartedState is the state every scenario starts in and returns to on reset
+const StartedState = "Started"
+
+// ScenarioState is the current state of a named scenario
+type ScenarioState struct {
+	Name  string `json:"name"`
+	State string `json:"state"`
+}
+
+type scenarioStore struct {
+	mu     sync.Mutex
+	states map[string]string
+}
+
+func newScenarioStore() *scenarioStore {
+	return &scenarioStore{states: make(map[string]string)}
+}
+
+// respond picks the response for the current state of the scenario and applies its transition
+func (s *scenarioStore) respond(scenario *Scenario, fallback Response) Response {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current := s.stateLocked(scenario.Name)
+	for _, candidate := range scenario.Responses {
+		if candidate.State != current {
+			continue
+		}
+		if candidate.Next != "" {
+			s.states[scenario.Name] = candidate.Next
+		}
+		return candidate.Response
+	}
+	return fallback
+}
+
+func (s *scenarioStore) stateLocked(name string) string {
+	if state, ok := s.states[name]; ok {
+		return state
+	}
+	return StartedState
+}
+
+func (s *scenarioStore) set(name, state string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.states[name] = state
+}
+
+func (s *scenarioStore) reset(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.states, name)
+}
+
+func (s *scenarioStore) resetAll() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.states = make(map[string]string)
+}
+
+// list returns the state of every scenario that is known or has been set
+func (s *scenarioStore) list(names []string) []ScenarioState {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	seen := make(map[string]bool)
+	var states []ScenarioState
+	for _, name := range names {
+		if !seen[name] {
+			seen[name] = true
+			states = append(states, ScenarioState{Name: name, State: s.stateLocked(name)})
+		}
+	}
+	for name, state := range s.states {
+		if !seen[name] {
+			seen[name] = true
+			states = append(states, ScenarioState{Name: name, State: state})
+		}
+	}
+
+	sort.Slice(states, func(i, j int) bool { return states[i].Name < states[j].Name })
+	return states
+}
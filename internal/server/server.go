@@ -0,0 +1,211 @@
+package server
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// AdminPrefix is the path prefix of the admin API of the local server
+const AdminPrefix = "/__mockthis"
+
+// Server serves mock endpoints loaded from endpoint files
+type Server struct {
+	mu        sync.RWMutex
+	endpoints []*Endpoint
+	initial   []*Endpoint
+	validate  func(data map[string]interface{}) error
+	check     func(endpoints []*Endpoint) error
+	scenarios *scenarioStore
+	journal   *journal
+	admin     *http.ServeMux
+
+	upstream    *httputil.ReverseProxy
+	upstreamURL *url.URL
+	cors        *CORS
+}
+
+// New creates a server for the given endpoints
+func New(endpoints []*Endpoint) (*Server, error) {
+	if err := checkDuplicates(endpoints); err != nil {
+		return nil, err
+	}
+
+	s := &Server{
+		endpoints: endpoints,
+		initial:   endpoints,
+		scenarios: newScenarioStore(),
+		journal:   &journal{},
+	}
+	s.admin = s.adminRoutes()
+	return s, nil
+}
+
+// Endpoints returns the endpoints currently served
+func (s *Server) Endpoints() []*Endpoint {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]*Endpoint(nil), s.endpoints...)
+}
+
+// SetValidator sets the function endpoint files added through the admin API are validated
+// with. It may rewrite the file data in place into the shape NewEndpoint reads.
+func (s *Server) SetValidator(validate func(data map[string]interface{}) error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.validate = validate
+}
+
+// SetEndpointCheck sets the function endpoints added through the admin API or reloaded from
+// files are checked with before they are served
+func (s *Server) SetEndpointCheck(check func(endpoints []*Endpoint) error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.check = check
+}
+
+// checkEndpoints checks endpoints with the function set with SetEndpointCheck
+func (s *Server) checkEndpoints(endpoints []*Endpoint) error {
+	s.mu.RLock()
+	check := s.check
+	s.mu.RUnlock()
+	if check == nil {
+		return nil
+	}
+	return check(endpoints)
+}
+
+// AddEndpoint serves a new endpoint, replacing the endpoint with the same name if there is one
+func (s *Server) AddEndpoint(endpoint *Endpoint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	endpoints := make([]*Endpoint, 0, len(s.endpoints)+1)
+	for _, existing := range s.endpoints {
+		if existing.Name != endpoint.Name {
+			endpoints = append(endpoints, existing)
+		}
+	}
+	endpoints = append(endpoints, endpoint)
+
+	if err := checkDuplicates(endpoints); err != nil {
+		return err
+	}
+	s.endpoints = endpoints
+	return nil
+}
+
+// RemoveEndpoint stops serving the endpoint with the given name
+func (s *Server) RemoveEndpoint(name string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, existing := range s.endpoints {
+		if existing.Name == name {
+			s.endpoints = append(s.endpoints[:i:i], s.endpoints[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Reset restores the endpoints the server was started with, resets every scenario and
+// clears the request journal
+func (s *Server) Reset() {
+	s.mu.Lock()
+	s.endpoints = s.initial
+	s.mu.Unlock()
+
+	s.scenarios.resetAll()
+	s.journal.clear()
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == AdminPrefix || strings.HasPrefix(r.URL.Path, AdminPrefix+"/") {
+		s.admin.ServeHTTP(w, r)
+		return
+	}
+
+	entry := s.journal.record(r)
+
+	endpoint := s.match(r)
+	if endpoint == nil {
+		if isPreflight(r) && s.preflight(w, r) {
+			return
+		}
+		if s.proxy(w, r) {
+			s.journal.annotate(entry, "", true)
+			return
+		}
+		s.journal.capture(r)
+		log.Printf("%s %s -> %d no matching endpoint", r.Method, r.URL.RequestURI(), http.StatusNotFound)
+		http.Error(w, "No mock endpoint matches this request", http.StatusNotFound)
+		return
+	}
+	s.journal.annotate(entry, endpoint.Name, false)
+	s.journal.capture(r)
+
+	if cors := s.corsFor(endpoint); cors != nil && r.Header.Get("Origin") != "" {
+		cors.allowOrigin(w, r)
+	}
+
+	if !authorized(r, endpoint.Auth) {
+		log.Printf("%s %s -> %d mocked by %s", r.Method, r.URL.RequestURI(), http.StatusUnauthorized, endpoint.Name)
+		if authType, _ := endpoint.Auth["type"].(string); authType == "basic" {
+			w.Header().Set("WWW-Authenticate", `Basic realm="mockthis"`)
+		}
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	response := endpoint.Response
+	if endpoint.Scenario != nil {
+		response = s.scenarios.respond(endpoint.Scenario, response)
+	}
+
+	log.Printf("%s %s -> %d mocked by %s", r.Method, r.URL.RequestURI(), response.Status, endpoint.Name)
+	response.write(w)
+}
+
+// match finds the endpoint for a request, preferring the most specific one
+func (s *Server) match(r *http.Request) *Endpoint {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var matched *Endpoint
+	for _, endpoint := range s.endpoints {
+		if endpoint.matches(r) && (matched == nil || endpoint.moreSpecific(matched)) {
+			matched = endpoint
+		}
+	}
+	return matched
+}
+
+func (s *Server) scenarioNames() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var names []string
+	for _, endpoint := range s.endpoints {
+		if endpoint.Scenario != nil {
+			names = append(names, endpoint.Scenario.Name)
+		}
+	}
+	return names
+}
+
+func checkDuplicates(endpoints []*Endpoint) error {
+	seen := make(map[string]string)
+	for _, endpoint := range endpoints {
+		key := endpoint.Route()
+		if other, exists := seen[key]; exists {
+			return fmt.Errorf("endpoints %s and %s both serve %s", other, endpoint.Name, key)
+		}
+		seen[key] = endpoint.Name
+	}
+	return nil
+}
@@ -0,0 +1,460 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nicobistolfi/mockthis-cli/internal/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func mustEndpoint(t *testing.T, name, yamlInput string) *Endpoint {
+	t.Helper()
+	data, err := utils.ParseYAML(yamlInput)
+	assert.NoError(t, err)
+	endpoint, err := NewEndpoint(name, data["endpoint"].(map[string]interface{}))
+	assert.NoError(t, err)
+	return endpoint
+}
+
+func doRequest(t *testing.T, handler http.Handler, method, target, body string, headers map[string]string) (int, string) {
+	t.Helper()
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	responseBody, _ := io.ReadAll(rec.Body)
+	return rec.Code, string(responseBody)
+}
+
+func checkoutServer(t *testing.T) *Server {
+	t.Helper()
+	order := mustEndpoint(t, "order", `
+endpoint:
+  scenario:
+    name: checkout
+    responses:
+      - state: Started
+        response:
+          body: pending
+      - state: paid
+        response:
+          body: paid
+  response:
+    method: GET
+    content-type: text/plain
+`)
+	pay := mustEndpoint(t, "pay", `
+endpoint:
+  scenario:
+    name: checkout
+    responses:
+      - state: Started
+        next: paid
+  response:
+    method: POST
+    status: "201"
+    body: ok
+`)
+	srv, err := New([]*Endpoint{order, pay})
+	assert.NoError(t, err)
+	return srv
+}
+
+func TestScenarioTransitions(t *testing.T) {
+	srv := checkoutServer(t)
+
+	status, body := doRequest(t, srv, "GET", "/order", "", nil)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "pending", body)
+
+	status, body = doRequest(t, srv, "POST", "/pay", "", nil)
+	assert.Equal(t, http.StatusCreated, status)
+	assert.Equal(t, "ok", body)
+
+	_, body = doRequest(t, srv, "GET", "/order", "", nil)
+	assert.Equal(t, "paid", body)
+
+	// No scenario response for "paid" on /pay, so the endpoint response is used
+	status, _ = doRequest(t, srv, "POST", "/pay", "", nil)
+	assert.Equal(t, http.StatusCreated, status)
+}
+
+func TestScenarioAdminAPI(t *testing.T) {
+	srv := checkoutServer(t)
+
+	status, _ := doRequest(t, srv, "PUT", AdminPrefix+"/scenarios/checkout/state", `{"state": "paid"}`, nil)
+	assert.Equal(t, http.StatusNoContent, status)
+	_, body := doRequest(t, srv, "GET", "/order", "", nil)
+	assert.Equal(t, "paid", body)
+
+	_, body = doRequest(t, srv, "GET", AdminPrefix+"/scenarios", "", nil)
+	assert.JSONEq(t, `[{"name": "checkout", "state": "paid"}]`, body)
+
+	status, _ = doRequest(t, srv, "POST", AdminPrefix+"/scenarios/reset", "", nil)
+	assert.Equal(t, http.StatusNoContent, status)
+	_, body = doRequest(t, srv, "GET", "/order", "", nil)
+	assert.Equal(t, "pending", body)
+}
+
+func TestAuthorization(t *testing.T) {
+	endpoint := mustEndpoint(t, "secure", `
+endpoint:
+  auth:
+    type: bearer
+    properties:
+      token: secret
+  response:
+    body: hello
+`)
+	srv, err := New([]*Endpoint{endpoint})
+	assert.NoError(t, err)
+
+	status, _ := doRequest(t, srv, "GET", "/secure", "", nil)
+	assert.Equal(t, http.StatusUnauthorized, status)
+
+	status, body := doRequest(t, srv, "GET", "/secure", "", map[string]string{"Authorization": "Bearer secret"})
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "hello", body)
+}
+
+func TestDuplicateEndpoints(t *testing.T) {
+	first := mustEndpoint(t, "first", "endpoint:\n  path: /same\n  response:\n    body: a\n")
+	second := mustEndpoint(t, "second", "endpoint:\n  path: /same\n  response:\n    body: b\n")
+
+	_, err := New([]*Endpoint{first, second})
+	assert.Error(t, err)
+}
+
+func TestQueryMatching(t *testing.T) {
+	all := mustEndpoint(t, "all", "endpoint:\n  path: /search\n  response:\n    body: all\n")
+	filtered := mustEndpoint(t, "filtered", "endpoint:\n  path: /search?q=mock\n  response:\n    body: filtered\n")
+	srv, err := New([]*Endpoint{all, filtered})
+	assert.NoError(t, err)
+
+	_, body := doRequest(t, srv, "GET", "/search?q=mock&page=2", "", nil)
+	assert.Equal(t, "filtered", body)
+
+	_, body = doRequest(t, srv, "GET", "/search?q=other", "", nil)
+	assert.Equal(t, "all", body)
+}
+
+func TestPathPatterns(t *testing.T) {
+	endpoints := []*Endpoint{
+		mustEndpoint(t, "user", "endpoint:\n  path: /users/{id}\n  response:\n    body: user\n"),
+		mustEndpoint(t, "me", "endpoint:\n  path: /users/me\n  response:\n    body: me\n"),
+		mustEndpoint(t, "orders", "endpoint:\n  path: /users/*/orders\n  response:\n    body: orders\n"),
+		mustEndpoint(t, "files", "endpoint:\n  path: /files/**\n  response:\n    body: files\n"),
+		mustEndpoint(t, "readme", "endpoint:\n  path: /files/README\n  response:\n    body: readme\n"),
+		mustEndpoint(t, "search", "endpoint:\n  path: /search?q=*\n  response:\n    body: search\n"),
+	}
+	srv, err := New(endpoints)
+	assert.NoError(t, err)
+
+	tests := map[string]string{
+		"/users/42":          "user",
+		"/users/me":          "me",
+		"/users/42/orders":   "orders",
+		"/files":             "files",
+		"/files/a/b/c.txt":   "files",
+		"/files/README":      "readme",
+		"/search?q=anything": "search",
+	}
+	for target, expected := range tests {
+		status, body := doRequest(t, srv, "GET", target, "", nil)
+		assert.Equal(t, http.StatusOK, status, target)
+		assert.Equal(t, expected, body, target)
+	}
+
+	for _, target := range []string{"/users", "/users/", "/users/42/orders/1", "/search"} {
+		status, _ := doRequest(t, srv, "GET", target, "", nil)
+		assert.Equal(t, http.StatusNotFound, status, target)
+	}
+
+	assert.Equal(t, "/users/1/orders", endpoints[2].ExamplePath())
+	assert.Equal(t, "/search?q=1", endpoints[5].ExamplePath())
+
+	// Parameters match the same requests whatever their name
+	other := mustEndpoint(t, "other", "endpoint:\n  path: /users/{name}\n  response:\n    body: other\n")
+	_, err = New([]*Endpoint{endpoints[0], other})
+	assert.Error(t, err)
+
+	for _, path := range []string{"/users/{}", "/users/id-{id}", "/files/**/x", "/files/a*"} {
+		_, err := NewEndpoint("invalid", map[string]interface{}{"path": path})
+		assert.Error(t, err, path)
+	}
+}
+
+func TestUpstreamPassthrough(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("X-Upstream", r.Header.Get("X-Client"))
+		w.WriteHeader(http.StatusAccepted)
+		_, _ = w.Write(append([]byte(r.Method+" "+r.URL.RequestURI()+" "), body...))
+	}))
+	defer upstream.Close()
+
+	endpoint := mustEndpoint(t, "mocked", "endpoint:\n  response:\n    body: mocked\n")
+	srv, err := New([]*Endpoint{endpoint})
+	assert.NoError(t, err)
+	upstreamURL, _ := url.Parse(upstream.URL)
+	srv.SetUpstream(upstreamURL)
+
+	_, body := doRequest(t, srv, "GET", "/mocked", "", nil)
+	assert.Equal(t, "mocked", body)
+
+	req := httptest.NewRequest("POST", "/orders?page=2", strings.NewReader("payload"))
+	req.Header.Set("X-Client", "cli")
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusAccepted, rec.Code)
+	assert.Equal(t, "cli", rec.Header().Get("X-Upstream"))
+	assert.Equal(t, "POST /orders?page=2 payload", rec.Body.String())
+
+	// The journal keeps the body the proxy streamed
+	entries := srv.journal.find(RequestPattern{Path: "/orders"})
+	assert.Len(t, entries, 1)
+	assert.Equal(t, "payload", entries[0].Body)
+	assert.True(t, entries[0].Proxied)
+}
+
+func TestAdminEndpointsAndJournal(t *testing.T) {
+	srv, err := New(nil)
+	assert.NoError(t, err)
+
+	status, _ := doRequest(t, srv, "PUT", AdminPrefix+"/endpoints/orders/create", `
+endpoint:
+  path: /orders
+  response:
+    method: POST
+    status: "201"
+    body: created
+`, nil)
+	assert.Equal(t, http.StatusNoContent, status)
+
+	_, body := doRequest(t, srv, "GET", AdminPrefix+"/endpoints", "", nil)
+	assert.JSONEq(t, `[{"name": "orders/create", "method": "POST", "path": "/orders", "status": 201}]`, body)
+
+	doRequest(t, srv, "POST", "/orders", `{"item": "book", "qty": 1}`, nil)
+	doRequest(t, srv, "POST", "/orders", `{"qty": 1, "item": "book"}`, nil)
+	doRequest(t, srv, "POST", "/orders", `{"item": "pen"}`, nil)
+	doRequest(t, srv, "GET", "/missing", "", nil)
+
+	_, body = doRequest(t, srv, "POST", AdminPrefix+"/requests/count", `{"method": "POST", "path": "/orders", "body": "{\"item\": \"book\", \"qty\": 1}"}`, nil)
+	assert.JSONEq(t, `{"count": 2}`, body)
+
+	_, body = doRequest(t, srv, "POST", AdminPrefix+"/requests/count", "", nil)
+	assert.JSONEq(t, `{"count": 4}`, body)
+
+	status, _ = doRequest(t, srv, "DELETE", AdminPrefix+"/endpoints/orders/create", "", nil)
+	assert.Equal(t, http.StatusNoContent, status)
+	status, _ = doRequest(t, srv, "POST", "/orders", "", nil)
+	assert.Equal(t, http.StatusNotFound, status)
+
+	status, _ = doRequest(t, srv, "POST", AdminPrefix+"/reset", "", nil)
+	assert.Equal(t, http.StatusNoContent, status)
+	_, body = doRequest(t, srv, "GET", AdminPrefix+"/requests", "", nil)
+	assert.JSONEq(t, `[]`, body)
+}
+
+func TestEndpointCheck(t *testing.T) {
+	public, err := NewEndpoint("public", map[string]interface{}{"path": "/public"})
+	assert.NoError(t, err)
+	srv, err := New([]*Endpoint{public})
+	assert.NoError(t, err)
+	srv.SetEndpointCheck(func(endpoints []*Endpoint) error {
+		for _, endpoint := range endpoints {
+			if endpoint.Auth != nil {
+				return fmt.Errorf("endpoint %s uses auth", endpoint.Name)
+			}
+		}
+		return nil
+	})
+
+	status, body := doRequest(t, srv, "PUT", AdminPrefix+"/endpoints/private", `
+endpoint:
+  path: /private
+  auth:
+    type: bearer
+    properties:
+      token: secret
+`, nil)
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.Contains(t, body, "endpoint private uses auth")
+
+	private, err := NewEndpoint("private", map[string]interface{}{"path": "/private", "auth": map[string]interface{}{"type": "bearer"}})
+	assert.NoError(t, err)
+	assert.EqualError(t, srv.ReplaceEndpoints([]*Endpoint{private}), "endpoint private uses auth")
+	assert.Equal(t, []*Endpoint{public}, srv.Endpoints())
+}
+
+func TestJournalReadsBodiesLazily(t *testing.T) {
+	// Recording a request doesn't wait for its body, which would block here
+	body, writer := io.Pipe()
+	r := httptest.NewRequest("POST", "/upload", body)
+	j := &journal{}
+	j.record(r)
+
+	go func() {
+		_, _ = io.WriteString(writer, "first")
+		_, _ = io.WriteString(writer, " second")
+		writer.Close()
+	}()
+	read, err := io.ReadAll(r.Body)
+	assert.NoError(t, err)
+	assert.Equal(t, "first second", string(read))
+
+	entries := j.find(RequestPattern{})
+	assert.Len(t, entries, 1)
+	assert.Equal(t, "first second", entries[0].Body)
+}
+
+func TestJournalBodyLimits(t *testing.T) {
+	j := &journal{}
+	large := strings.Repeat("x", maxJournalBody+10)
+	for i := 0; i < maxJournalBytes/maxJournalBody+2; i++ {
+		r := httptest.NewRequest("POST", "/upload", strings.NewReader(large))
+		j.record(r)
+		j.capture(r)
+	}
+
+	entries := j.find(RequestPattern{})
+	assert.Len(t, entries, maxJournalBytes/maxJournalBody)
+	assert.Len(t, entries[0].Body, maxJournalBody)
+	assert.LessOrEqual(t, j.size, maxJournalBytes)
+}
+
+func TestWatchReloadsEndpoints(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "hello.txt")
+	assert.NoError(t, os.WriteFile(file, []byte("first"), 0644))
+
+	load := func() ([]*Endpoint, error) {
+		body, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		if string(body) == "invalid" {
+			return nil, errors.New("invalid endpoint file")
+		}
+		endpoint, err := NewEndpoint("hello", map[string]interface{}{"response": map[string]interface{}{"body": string(body)}})
+		return []*Endpoint{endpoint}, err
+	}
+
+	endpoints, err := load()
+	assert.NoError(t, err)
+	srv, err := New(endpoints)
+	assert.NoError(t, err)
+
+	done := make(chan struct{})
+	defer close(done)
+	assert.NoError(t, srv.Watch(dir, load, done))
+
+	waitForBody := func(expected string) {
+		t.Helper()
+		assert.Eventually(t, func() bool {
+			_, body := doRequest(t, srv, "GET", "/hello", "", nil)
+			return body == expected
+		}, 5*time.Second, 20*time.Millisecond)
+	}
+
+	assert.NoError(t, os.WriteFile(file, []byte("second"), 0644))
+	waitForBody("second")
+
+	// A file that fails to load keeps the previous endpoints
+	assert.NoError(t, os.WriteFile(file, []byte("invalid"), 0644))
+	time.Sleep(5 * reloadDelay)
+	waitForBody("second")
+
+	assert.NoError(t, os.WriteFile(file, []byte("third"), 0644))
+	waitForBody("third")
+}
+
+func TestCORS(t *testing.T) {
+	endpoint := mustEndpoint(t, "orders", `
+endpoint:
+  cors:
+    origins:
+      - https://app.example.com
+    headers:
+      - Content-Type
+    credentials: true
+    max-age: 600
+  response:
+    method: POST
+    body: created
+`)
+	open := mustEndpoint(t, "health", "endpoint:\n  response:\n    body: ok\n")
+	srv, err := New([]*Endpoint{endpoint, open})
+	assert.NoError(t, err)
+
+	preflight := map[string]string{"Origin": "https://app.example.com", "Access-Control-Request-Method": "POST"}
+	req := httptest.NewRequest("OPTIONS", "/orders", nil)
+	for key, value := range preflight {
+		req.Header.Set(key, value)
+	}
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	assert.Equal(t, "https://app.example.com", rec.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "POST", rec.Header().Get("Access-Control-Allow-Methods"))
+	assert.Equal(t, "Content-Type", rec.Header().Get("Access-Control-Allow-Headers"))
+	assert.Equal(t, "true", rec.Header().Get("Access-Control-Allow-Credentials"))
+	assert.Equal(t, "600", rec.Header().Get("Access-Control-Max-Age"))
+
+	req = httptest.NewRequest("OPTIONS", "/orders", nil)
+	req.Header.Set("Origin", "https://evil.example.com")
+	req.Header.Set("Access-Control-Request-Method", "POST")
+	rec = httptest.NewRecorder()
+	srv.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+
+	req = httptest.NewRequest("POST", "/orders", nil)
+	req.Header.Set("Origin", "https://app.example.com")
+	rec = httptest.NewRecorder()
+	srv.ServeHTTP(rec, req)
+	assert.Equal(t, "https://app.example.com", rec.Header().Get("Access-Control-Allow-Origin"))
+
+	// Without a server policy, endpoints without a cors block get no CORS headers
+	status, _ := doRequest(t, srv, "OPTIONS", "/health", "", map[string]string{"Origin": "https://app.example.com", "Access-Control-Request-Method": "GET"})
+	assert.Equal(t, http.StatusNotFound, status)
+
+	srv.SetCORS(&CORS{Origins: []string{"*"}})
+	req = httptest.NewRequest("OPTIONS", "/health", nil)
+	req.Header.Set("Origin", "https://app.example.com")
+	req.Header.Set("Access-Control-Request-Method", "GET")
+	rec = httptest.NewRecorder()
+	srv.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	assert.Equal(t, "*", rec.Header().Get("Access-Control-Allow-Origin"))
+}
+
+func TestBase64Body(t *testing.T) {
+	endpoint := mustEndpoint(t, "image", `
+endpoint:
+  response:
+    content-type: image/png
+    charset: ""
+    body_base64: iVBORw0KGgoAAA==
+`)
+	srv, err := New([]*Endpoint{endpoint})
+	assert.NoError(t, err)
+
+	req := httptest.NewRequest("GET", "/image", nil)
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, req)
+	assert.Equal(t, []byte("\x89PNG\r\n\x1a\n\x00\x00"), rec.Body.Bytes())
+	assert.Equal(t, "10", rec.Header().Get("Content-Length"))
+	assert.Equal(t, "image/png", rec.Header().Get("Content-Type"))
+}
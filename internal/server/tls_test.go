@@ -0,0 +1,67 @@
+package server
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenerateCertificate(t *testing.T) {
+	certificate, caPEM, err := GenerateCertificate([]string{"localhost", "127.0.0.1"})
+	assert.NoError(t, err)
+
+	endpoint := mustEndpoint(t, "hello", "endpoint:\n  response:\n    body: hello\n")
+	srv, err := New([]*Endpoint{endpoint})
+	assert.NoError(t, err)
+
+	ts := httptest.NewUnstartedServer(srv)
+	ts.TLS = TLSConfig(certificate, nil)
+	ts.StartTLS()
+	defer ts.Close()
+
+	roots, err := LoadCertPool(caPEM)
+	assert.NoError(t, err)
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots}}}
+
+	resp, err := client.Get(ts.URL + "/hello")
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	assert.Equal(t, "hello", string(body))
+}
+
+func TestMutualTLSAuth(t *testing.T) {
+	clientCert := &x509.Certificate{
+		Subject:  pkix.Name{CommonName: "billing", Organization: []string{"Payments"}},
+		DNSNames: []string{"billing.internal"},
+	}
+
+	tests := []struct {
+		name     string
+		auth     map[string]interface{}
+		peer     *x509.Certificate
+		expected bool
+	}{
+		{"Common name", map[string]interface{}{"type": "mtls", "properties": map[string]interface{}{"subject": "billing"}}, clientCert, true},
+		{"Distinguished name", map[string]interface{}{"type": "mtls", "properties": map[string]interface{}{"subject": "CN=billing,O=Payments"}}, clientCert, true},
+		{"SAN", map[string]interface{}{"type": "mtls", "properties": map[string]interface{}{"san": "billing.internal"}}, clientCert, true},
+		{"Wrong SAN", map[string]interface{}{"type": "mtls", "properties": map[string]interface{}{"subject": "billing", "san": "orders.internal"}}, clientCert, false},
+		{"No certificate", map[string]interface{}{"type": "mtls", "properties": map[string]interface{}{"subject": "billing"}}, nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "https://localhost/secure", nil)
+			if tt.peer != nil {
+				req.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{tt.peer}}
+			}
+			assert.Equal(t, tt.expected, authorized(req, tt.auth))
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package server
+
+import (
+	"io/fs"
+	"log"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// reloadDelay groups the bursts of events editors produce when saving a file into one reload
+const reloadDelay = 100 * time.Millisecond
+
+// ReplaceEndpoints atomically swaps the served endpoints. Requests already matched keep
+// the endpoint they matched; endpoints added through the admin API are dropped.
+func (s *Server) ReplaceEndpoints(endpoints []*Endpoint) error {
+	if err := checkDuplicates(endpoints); err != nil {
+		return err
+	}
+	if err := s.checkEndpoints(endpoints); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.endpoints = endpoints
+	s.initial = endpoints
+	return nil
+}
+
+// Watch reloads the endpoints with load whenever a file under dir changes, until done is
+// closed. When load fails the error is logged and the current endpoints keep being served.
+func (s *Server) Watch(dir string, load func() ([]*Endpoint, error), done <-chan struct{}) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+
+	if err := watchTree(watcher, dir); err != nil {
+		watcher.Close()
+		return err
+	}
+
+	go func() {
+		defer watcher.Close()
+
+		timer := time.NewTimer(reloadDelay)
+		timer.Stop()
+
+		for {
+			select {
+			case <-done:
+				timer.Stop()
+				return
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				// New directories are not watched by fsnotify until they are added
+				if event.Has(fsnotify.Create) && !strings.HasPrefix(filepath.Base(event.Name), ".") {
+					_ = watchTree(watcher, event.Name)
+				}
+				timer.Reset(reloadDelay)
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				log.Printf("Error watching %s: %v", dir, err)
+			case <-timer.C:
+				s.reload(load)
+			}
+		}
+	}()
+
+	return nil
+}
+
+func (s *Server) reload(load func() ([]*Endpoint, error)) {
+	endpoints, err := load()
+	if err == nil {
+		err = s.ReplaceEndpoints(endpoints)
+	}
+	if err != nil {
+		log.Printf("Not reloading endpoints: %v", err)
+		return
+	}
+	log.Printf("Reloaded %d endpoints", len(endpoints))
+}
+
+// watchTree adds root and the directories under it to the watcher, skipping hidden ones
+func watchTree(watcher *fsnotify.Watcher, root string) error {
+	return filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		if path != root && strings.HasPrefix(d.Name(), ".") {
+			return filepath.SkipDir
+		}
+		return watcher.Add(path)
+	})
+}
@@ -0,0 +1,224 @@
+// Package state records which endpoint files were created as which remote endpoints, in a
+// .mockthis/state.json file next to the endpoint files.
+package state
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+const (
+	// Dir is the directory of the state file, in the root of a directory of endpoint files
+	Dir = ".mockthis"
+	// File is the name of the state file in Dir
+	File = "state.json"
+
+	lockFile = "state.lock"
+	version  = 1
+)
+
+// Entry maps an endpoint file to the endpoint created from it
+type Entry struct {
+	// File is the path of the endpoint file, relative to the root of the state
+	File string `json:"file"`
+	// Name is the name of the endpoint, its file path without extension
+	Name    string `json:"name"`
+	ID      string `json:"id"`
+	MockURL string `json:"mockUrl,omitempty"`
+	// Hash is the hash of the content of the file when the endpoint was created or pulled
+	Hash string `json:"hash"`
+}
+
+// State is the content of a state file
+type State struct {
+	Version   int     `json:"version"`
+	Endpoints []Entry `json:"endpoints"`
+
+	root string
+}
+
+// Root returns the directory holding the state of the endpoint files of dir: the closest
+// directory from dir up holding a state file, or dir itself when there is none. The search
+// stops below the home directory, whose .mockthis directory holds the configuration.
+func Root(dir string) string {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return dir
+	}
+	home, _ := os.UserHomeDir()
+	for current := abs; ; {
+		if current == home && current != abs {
+			return abs
+		}
+		if _, err := os.Stat(filepath.Join(current, Dir, File)); err == nil {
+			return current
+		}
+		parent := filepath.Dir(current)
+		if parent == current {
+			return abs
+		}
+		current = parent
+	}
+}
+
+// Load reads the state of root, which is empty when root has no state file
+func Load(root string) (*State, error) {
+	// Reading doesn't create the state directory
+	if _, err := os.Stat(filepath.Join(root, Dir, File)); errors.Is(err, os.ErrNotExist) {
+		return &State{Version: version, Endpoints: []Entry{}, root: root}, nil
+	}
+
+	var s *State
+	err := withLock(root, func() error {
+		var err error
+		s, err = read(root)
+		return err
+	})
+	return s, err
+}
+
+// Update reads the state of root, calls fn to change it and writes it back when fn succeeds.
+// The state is locked in the meantime, for concurrent commands not to lose changes.
+func Update(root string, fn func(*State) error) error {
+	return withLock(root, func() error {
+		s, err := read(root)
+		if err != nil {
+			return err
+		}
+		if err := fn(s); err != nil {
+			return err
+		}
+		return s.write()
+	})
+}
+
+func withLock(root string, fn func() error) error {
+	if err := os.MkdirAll(filepath.Join(root, Dir), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(filepath.Join(root, Dir, lockFile), os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if err := lock(f); err != nil {
+		return fmt.Errorf("error locking the state: %v", err)
+	}
+	defer unlock(f)
+	return fn()
+}
+
+func read(root string) (*State, error) {
+	s := &State{Version: version, Endpoints: []Entry{}, root: root}
+	content, err := os.ReadFile(filepath.Join(root, Dir, File))
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(content, s); err != nil {
+		return nil, fmt.Errorf("invalid state file %s: %v", filepath.Join(root, Dir, File), err)
+	}
+	if s.Version != version {
+		return nil, fmt.Errorf("unsupported state version %d, this version of mockthis reads version %d", s.Version, version)
+	}
+	return s, nil
+}
+
+// write replaces the state file through a temporary file, so readers never see half of it
+func (s *State) write() error {
+	sort.Slice(s.Endpoints, func(i, j int) bool { return s.Endpoints[i].File < s.Endpoints[j].File })
+	content, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Join(s.root, Dir)
+	tmp, err := os.CreateTemp(dir, File+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(append(content, '\n')); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), filepath.Join(dir, File))
+}
+
+// Rel returns the path of a file relative to the root of the state, as stored in entries
+func (s *State) Rel(path string) string {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return filepath.ToSlash(path)
+	}
+	rel, err := filepath.Rel(s.root, abs)
+	if err != nil {
+		return filepath.ToSlash(path)
+	}
+	return filepath.ToSlash(rel)
+}
+
+// Put adds the entry of a file, replacing the previous entry of the file and any entry of
+// the same endpoint
+func (s *State) Put(entry Entry) {
+	kept := s.Endpoints[:0]
+	for _, e := range s.Endpoints {
+		if e.File != entry.File && e.ID != entry.ID {
+			kept = append(kept, e)
+		}
+	}
+	s.Endpoints = append(kept, entry)
+}
+
+// RemoveID removes the entries of an endpoint, reporting whether there was one
+func (s *State) RemoveID(id string) bool {
+	kept := s.Endpoints[:0]
+	for _, e := range s.Endpoints {
+		if e.ID != id {
+			kept = append(kept, e)
+		}
+	}
+	removed := len(kept) != len(s.Endpoints)
+	s.Endpoints = kept
+	return removed
+}
+
+// ByFile returns the entry of a file, given by its path
+func (s *State) ByFile(path string) (Entry, bool) {
+	rel := s.Rel(path)
+	for _, e := range s.Endpoints {
+		if e.File == rel {
+			return e, true
+		}
+	}
+	return Entry{}, false
+}
+
+// ByName returns the entries of an endpoint name
+func (s *State) ByName(name string) []Entry {
+	var entries []Entry
+	for _, e := range s.Endpoints {
+		if e.Name == name {
+			entries = append(entries, e)
+		}
+	}
+	return entries
+}
+
+// Hash returns the hash recorded for the content of an endpoint file
+func Hash(content []byte) string {
+	sum := sha256.Sum256(content)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,109 @@
+package state
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUpdate(t *testing.T) {
+	root := t.TempDir()
+
+	s, err := Load(root)
+	assert.NoError(t, err)
+	assert.Empty(t, s.Endpoints)
+
+	assert.NoError(t, Update(root, func(s *State) error {
+		s.Put(Entry{File: s.Rel(filepath.Join(root, "users", "get.yml")), Name: "users/get", ID: "e1"})
+		s.Put(Entry{File: "orders.yml", Name: "orders", ID: "e2"})
+		return nil
+	}))
+
+	s, err = Load(root)
+	assert.NoError(t, err)
+	entry, ok := s.ByFile(filepath.Join(root, "users", "get.yml"))
+	assert.True(t, ok)
+	assert.Equal(t, Entry{File: "users/get.yml", Name: "users/get", ID: "e1"}, entry)
+	assert.Len(t, s.ByName("orders"), 1)
+
+	// Recreating an endpoint replaces the entry of its file
+	assert.NoError(t, Update(root, func(s *State) error {
+		s.Put(Entry{File: "orders.yml", Name: "orders", ID: "e3"})
+		assert.True(t, s.RemoveID("e1"))
+		assert.False(t, s.RemoveID("missing"))
+		return nil
+	}))
+	s, err = Load(root)
+	assert.NoError(t, err)
+	assert.Equal(t, []Entry{{File: "orders.yml", Name: "orders", ID: "e3"}}, s.Endpoints)
+
+	// A failed update changes nothing
+	assert.Error(t, Update(root, func(s *State) error {
+		s.Put(Entry{File: "lost.yml", ID: "e4"})
+		return fmt.Errorf("failed")
+	}))
+	s, _ = Load(root)
+	assert.Len(t, s.Endpoints, 1)
+}
+
+func TestConcurrentUpdates(t *testing.T) {
+	root := t.TempDir()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			assert.NoError(t, Update(root, func(s *State) error {
+				s.Put(Entry{File: fmt.Sprintf("e%d.yml", i), ID: fmt.Sprintf("e%d", i)})
+				return nil
+			}))
+		}(i)
+	}
+	wg.Wait()
+
+	s, err := Load(root)
+	assert.NoError(t, err)
+	assert.Len(t, s.Endpoints, 20)
+}
+
+func TestRoot(t *testing.T) {
+	root := t.TempDir()
+	nested := filepath.Join(root, "payments", "charges")
+	assert.NoError(t, os.MkdirAll(nested, 0755))
+
+	assert.Equal(t, nested, Root(nested))
+
+	assert.NoError(t, Update(root, func(*State) error { return nil }))
+	assert.Equal(t, root, Root(nested))
+}
+
+func TestRootStopsAtHome(t *testing.T) {
+	// The state of the home directory isn't the state of the directories under it
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+	nested := filepath.Join(home, "mocks")
+	assert.NoError(t, os.MkdirAll(nested, 0755))
+	assert.NoError(t, Update(home, func(*State) error { return nil }))
+
+	assert.Equal(t, nested, Root(nested))
+	assert.Equal(t, home, Root(home))
+}
+
+func TestHash(t *testing.T) {
+	assert.Equal(t, "sha256:e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855", Hash(nil))
+}
+
+func TestLoadWithoutState(t *testing.T) {
+	root := t.TempDir()
+	s, err := Load(root)
+	assert.NoError(t, err)
+	assert.Empty(t, s.Endpoints)
+	_, err = os.Stat(filepath.Join(root, Dir))
+	assert.True(t, os.IsNotExist(err))
+}
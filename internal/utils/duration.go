@@ -0,0 +1,36 @@
+package utils
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+var dayUnits = regexp.MustCompile(`(\d+(?:\.\d+)?)([dw])`)
+
+// ParseDuration parses a duration like time.ParseDuration, also accepting days (d) and
+// weeks (w) of 24 and 168 hours. Eg. "7d", "1w2d" or "1d12h".
+func ParseDuration(s string) (time.Duration, error) {
+	var invalid error
+	hours := dayUnits.ReplaceAllStringFunc(s, func(match string) string {
+		parts := dayUnits.FindStringSubmatch(match)
+		n, err := strconv.ParseFloat(parts[1], 64)
+		if err != nil {
+			invalid = err
+		}
+		if parts[2] == "w" {
+			n *= 7
+		}
+		return strconv.FormatFloat(n*24, 'f', -1, 64) + "h"
+	})
+	if invalid != nil {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+
+	d, err := time.ParseDuration(hours)
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration %q, use a number and a unit: s, m, h, d or w. Eg. 90m, 2h or 7d", s)
+	}
+	return d, nil
+}
@@ -0,0 +1,30 @@
+package utils
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseDuration(t *testing.T) {
+	tests := map[string]time.Duration{
+		"2h":    2 * time.Hour,
+		"90m":   90 * time.Minute,
+		"7d":    7 * 24 * time.Hour,
+		"1d12h": 36 * time.Hour,
+		"1.5d":  36 * time.Hour,
+		"1w2d":  9 * 24 * time.Hour,
+	}
+	for input, expected := range tests {
+		d, err := ParseDuration(input)
+		if assert.NoError(t, err, input) {
+			assert.Equal(t, expected, d, input)
+		}
+	}
+
+	for _, invalid := range []string{"", "7", "d", "7 days", "2x"} {
+		_, err := ParseDuration(invalid)
+		assert.Error(t, err, invalid)
+	}
+}